@@ -0,0 +1,230 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"reflect"
+	"sync"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+var ErrAliasConflict = errors.New("flag and its alias are set to different values")
+
+// Logger logs the warnings of the package eg. the use of a deprecated flag. *log.Logger implements it.
+type Logger interface {
+	Printf(format string, args ...any)
+}
+
+var (
+	loggerMu sync.RWMutex
+	logger   Logger = log.New(os.Stderr, "", 0)
+)
+
+// SetLogger sets the logger of the warnings, which is a logger writing to stderr by default. A nil
+// logger disables the warnings.
+func SetLogger(l Logger) {
+	loggerMu.Lock()
+	defer loggerMu.Unlock()
+
+	logger = l
+}
+
+// warnOnce logs a warning unless the same warning is already logged for v, eg. when the bindings are
+// resolved again by ResolveFlags or a ConfigWatcher.
+func warnOnce(v *viper.Viper, format string, args ...any) {
+	msg := fmt.Sprintf(format, args...)
+
+	state := stateOf(v)
+	state.mu.Lock()
+	logged := state.warned[msg]
+	if !logged {
+		if state.warned == nil {
+			state.warned = map[string]bool{}
+		}
+
+		state.warned[msg] = true
+	}
+	state.mu.Unlock()
+
+	if logged {
+		return
+	}
+
+	loggerMu.RLock()
+	defer loggerMu.RUnlock()
+
+	if logger != nil {
+		logger.Printf("%s", msg)
+	}
+}
+
+// warnDeprecated logs a warning if the value of a binding is set under a deprecated name, either the
+// name of a deprecated binding or an alias.
+func warnDeprecated(v *viper.Viper, binding *FlagBinding, key string, origin Origin) {
+	if origin == OriginDefault || (key == binding.Name && binding.Deprecated == "") {
+		return
+	}
+
+	var used, instead string
+	switch origin {
+	case OriginFlag:
+		used, instead = "flag --"+key, "--"+binding.Name
+	case OriginEnv:
+		used, instead = "env "+envVarName(envPrefixOf(v), key), envVarName(envPrefixOf(v), binding.Name)
+	case OriginSource:
+		used, instead = "source key "+key, binding.Name
+	default:
+		used, instead = "config key "+key, binding.Name
+	}
+
+	if key == binding.Name {
+		warnOnce(v, "%s is deprecated: %s", used, binding.Deprecated)
+		return
+	}
+
+	warnOnce(v, "%s is deprecated, use %s instead", used, instead)
+}
+
+// bindingKey returns the name, or the alias, the value of a binding is set under and the origin of
+// the value. The name takes precedence over an alias if both are set in the same source, in which case
+// bindingKey returns ErrAliasConflict if the values are different.
+func bindingKey(v *viper.Viper, flags *pflag.FlagSet, binding *FlagBinding) (string, Origin, error) {
+	key, origin := binding.Name, valueOrigin(v, flags, binding.Name)
+	rank := originRank(v, origin, key)
+
+	for _, alias := range binding.Aliases {
+		aliasOrigin := valueOrigin(v, flags, alias)
+		aliasRank := originRank(v, aliasOrigin, alias)
+		switch {
+		case aliasOrigin == OriginDefault || aliasRank < rank:
+			continue
+		case aliasRank == rank:
+			if !sameValue(v, flags, binding, key, alias, origin) {
+				return key, origin, fmt.Errorf("%s and %s set in %s: %w", key, alias, origin, ErrAliasConflict)
+			}
+		default:
+			key, origin, rank = alias, aliasOrigin, aliasRank
+		}
+	}
+
+	return key, origin, nil
+}
+
+// sameValue returns true if the values set under two names of a binding in the same source are equal.
+func sameValue(v *viper.Viper, flags *pflag.FlagSet, binding *FlagBinding, name1, name2 string, origin Origin) bool {
+	if origin == OriginFlag {
+		return flags.Lookup(name1).Value.String() == flags.Lookup(name2).Value.String()
+	}
+
+	decode := func(name string) (any, error) {
+		raw, err := lookupValue(v, name)
+		if err != nil {
+			return nil, err
+		}
+
+		return decodeValue(binding.Target, raw)
+	}
+
+	val1, err1 := decode(name1)
+	val2, err2 := decode(name2)
+	if err1 != nil || err2 != nil {
+		// The error is reported when the value is resolved.
+		return true
+	}
+
+	return reflect.DeepEqual(val1, val2)
+}
+
+// defineAliases sets up the hidden flags of the aliases of a binding and hides the flag of a deprecated
+// binding. The flag of an alias has its own target and sets the target of the binding when it's set,
+// unless the flag of the binding is also set.
+func defineAliases(v *viper.Viper, flags *pflag.FlagSet, ft flagType, binding *FlagBinding) {
+	if binding.Deprecated != "" {
+		flag := flags.Lookup(binding.Name)
+		flag.Value = &deprecatedValue{Value: flag.Value, v: v, binding: *binding}
+		flag.Hidden = true
+	}
+
+	if len(binding.Aliases) > 0 {
+		flag := flags.Lookup(binding.Name)
+		flag.Value = &primaryValue{Value: flag.Value, flags: flags, binding: *binding}
+	}
+
+	for _, alias := range binding.Aliases {
+		target := reflect.New(reflect.TypeOf(binding.Target).Elem()).Interface()
+		ft.define(flags, target, alias, "", binding.Default, binding.Usage)
+
+		flag := flags.Lookup(alias)
+		flag.Value = &aliasValue{Value: flag.Value, target: target, v: v, flags: flags, alias: alias, binding: *binding}
+		flag.Hidden = true
+	}
+}
+
+// deprecatedValue is the pflag.Value of a deprecated binding, which logs a warning when it's set.
+type deprecatedValue struct {
+	pflag.Value
+	v       *viper.Viper
+	binding FlagBinding
+}
+
+func (d *deprecatedValue) Set(str string) error {
+	warnDeprecated(d.v, &d.binding, d.binding.Name, OriginFlag)
+	return d.Value.Set(str)
+}
+
+// primaryValue is the pflag.Value of a binding with aliases, which checks that no alias set before it
+// is set to a different value.
+type primaryValue struct {
+	pflag.Value
+	flags   *pflag.FlagSet
+	binding FlagBinding
+}
+
+func (p *primaryValue) Set(str string) error {
+	if err := p.Value.Set(str); err != nil {
+		return err
+	}
+
+	for _, alias := range p.binding.Aliases {
+		if flag := p.flags.Lookup(alias); flag != nil && flag.Changed && flag.Value.String() != p.Value.String() {
+			return fmt.Errorf("--%s and --%s: %w", p.binding.Name, alias, ErrAliasConflict)
+		}
+	}
+
+	return nil
+}
+
+// aliasValue is the pflag.Value of an alias, which logs a warning and sets the target of the binding
+// when it's set.
+type aliasValue struct {
+	pflag.Value
+	target  any
+	v       *viper.Viper
+	flags   *pflag.FlagSet
+	alias   string
+	binding FlagBinding
+}
+
+func (a *aliasValue) Set(str string) error {
+	if err := a.Value.Set(str); err != nil {
+		return err
+	}
+
+	warnDeprecated(a.v, &a.binding, a.alias, OriginFlag)
+
+	if flag := a.flags.Lookup(a.binding.Name); flag != nil && flag.Changed {
+		if flag.Value.String() != a.Value.String() {
+			return fmt.Errorf("--%s and --%s: %w", a.binding.Name, a.alias, ErrAliasConflict)
+		}
+
+		return nil
+	}
+
+	reflect.ValueOf(a.binding.Target).Elem().Set(reflect.ValueOf(a.target).Elem())
+
+	return nil
+}
@@ -0,0 +1,249 @@
+package cli_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/cybersamx/golib/cli"
+)
+
+type testLogger struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (l *testLogger) Printf(format string, args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.msgs = append(l.msgs, fmt.Sprintf(format, args...))
+}
+
+func TestInitFlags_Aliases(t *testing.T) {
+	logger := testLogger{}
+	SetLogger(&logger)
+	t.Cleanup(func() { SetLogger(nil) })
+
+	dir := t.TempDir()
+	cfgFile := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(cfgFile, []byte("old-db-url: postgres://file\n"), 0o600))
+
+	tests := []struct {
+		description string
+		args        []string
+		envs        map[string]string
+		config      bool
+		want        string
+		wantWarn    string
+		wantErr     bool
+	}{
+		{
+			description: "Default",
+			want:        "postgres://default",
+		},
+		{
+			description: "Alias flag",
+			args:        []string{"--old-db-url", "postgres://flag"},
+			want:        "postgres://flag",
+			wantWarn:    "flag --old-db-url is deprecated, use --db-url instead",
+		},
+		{
+			description: "Alias env",
+			envs:        map[string]string{"AL_OLD_DB_URL": "postgres://env"},
+			want:        "postgres://env",
+			wantWarn:    "env AL_OLD_DB_URL is deprecated, use AL_DB_URL instead",
+		},
+		{
+			description: "Alias config key",
+			config:      true,
+			want:        "postgres://file",
+			wantWarn:    "config key old-db-url is deprecated, use db-url instead",
+		},
+		{
+			description: "Name env takes precedence over alias config key",
+			envs:        map[string]string{"AL_DB_URL": "postgres://env"},
+			config:      true,
+			want:        "postgres://env",
+		},
+		{
+			description: "Alias flag takes precedence over name env",
+			args:        []string{"--old-db-url", "postgres://flag"},
+			envs:        map[string]string{"AL_DB_URL": "postgres://env"},
+			want:        "postgres://flag",
+			wantWarn:    "flag --old-db-url is deprecated, use --db-url instead",
+		},
+		{
+			description: "Same values",
+			args:        []string{"--db-url", "postgres://flag", "--old-db-url", "postgres://flag"},
+			want:        "postgres://flag",
+			wantWarn:    "flag --old-db-url is deprecated, use --db-url instead",
+		},
+		{
+			description: "Conflicting flags",
+			args:        []string{"--db-url", "postgres://flag", "--old-db-url", "postgres://other"},
+			wantErr:     true,
+		},
+		{
+			description: "Conflicting flags with alias first",
+			args:        []string{"--old-db-url", "postgres://other", "--db-url", "postgres://flag"},
+			wantErr:     true,
+		},
+		{
+			description: "Conflicting envs",
+			envs:        map[string]string{"AL_DB_URL": "postgres://env", "AL_OLDER_DB_URL": "postgres://other"},
+			wantErr:     true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			for key, val := range test.envs {
+				t.Setenv(key, val)
+			}
+
+			logger.mu.Lock()
+			logger.msgs = nil
+			logger.mu.Unlock()
+
+			var dbURL string
+			spec := Command{
+				Use: "app",
+				Bindings: []FlagBinding{
+					{
+						Name:    "db-url",
+						Aliases: []string{"old-db-url", "older-db-url"},
+						Target:  &dbURL,
+						Default: "postgres://default",
+					},
+				},
+				RunE: func(cmd *cobra.Command, args []string) error { return nil },
+			}
+
+			args := test.args
+			if test.config {
+				args = append(args, "--config", cfgFile)
+			}
+
+			// A conflict in env variables is found when the command is built, and in flags when the
+			// flags are parsed, where pflag doesn't wrap the error.
+			cmd, err := NewCommand(NewViper("al"), &spec)
+			if err == nil {
+				cmd.SetArgs(args)
+				cmd.SilenceUsage = true
+				cmd.SilenceErrors = true
+				err = cmd.Execute()
+			}
+
+			if test.wantErr {
+				assert.ErrorContains(t, err, ErrAliasConflict.Error())
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.want, dbURL)
+
+			logger.mu.Lock()
+			defer logger.mu.Unlock()
+
+			if test.wantWarn == "" {
+				assert.Empty(t, logger.msgs)
+			} else {
+				assert.Equal(t, []string{test.wantWarn}, logger.msgs)
+			}
+
+			// The aliases are hidden from the help.
+			assert.NotContains(t, cmd.UsageString(), "old-db-url")
+		})
+	}
+}
+
+func TestInitFlags_Deprecated(t *testing.T) {
+	logger := testLogger{}
+	SetLogger(&logger)
+	t.Cleanup(func() { SetLogger(nil) })
+
+	var debug bool
+	bindings := []FlagBinding{
+		{Name: "debug", Target: &debug, Deprecated: "use --log-level=debug instead"},
+	}
+
+	cmd := &cobra.Command{Use: "app", Run: func(cmd *cobra.Command, args []string) {}}
+	err := InitFlags(NewViper("dep"), cmd.Flags(), bindings)
+	require.NoError(t, err)
+
+	cmd.SetArgs([]string{"--debug"})
+	require.NoError(t, cmd.Execute())
+	assert.True(t, debug)
+	assert.Equal(t, []string{"flag --debug is deprecated: use --log-level=debug instead"}, logger.msgs)
+	assert.False(t, strings.Contains(cmd.UsageString(), "--debug"))
+
+	logger.msgs = nil
+	t.Setenv("DEP_DEBUG", "true")
+
+	debug = false
+	cmd = &cobra.Command{Use: "app", Run: func(cmd *cobra.Command, args []string) {}}
+	err = InitFlags(NewViper("dep"), cmd.Flags(), bindings)
+	require.NoError(t, err)
+	assert.True(t, debug)
+	assert.Equal(t, []string{"env DEP_DEBUG is deprecated: use --log-level=debug instead"}, logger.msgs)
+}
+
+func TestInitFlags_AliasConflict(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		description string
+		args        []string
+		want        string
+		wantErr     bool
+	}{
+		{description: "Name first", args: []string{"--new=y", "--old=x"}, wantErr: true},
+		{description: "Alias first", args: []string{"--old=x", "--new=y"}, wantErr: true},
+		{description: "Same values", args: []string{"--old=x", "--new=x"}, want: "x"},
+	}
+
+	for _, test := range tests {
+		var val string
+		bindings := []FlagBinding{
+			{Name: "new", Aliases: []string{"old"}, Target: &val},
+		}
+
+		// The conflict is found when the flags are parsed, without resolving the bindings.
+		flags := pflag.NewFlagSet("app", pflag.ContinueOnError)
+		err := InitFlags(NewViper("al"), flags, bindings)
+		require.NoError(t, err, test.description)
+
+		err = flags.Parse(test.args)
+		if test.wantErr {
+			assert.ErrorContains(t, err, "--new and --old: "+ErrAliasConflict.Error(), test.description)
+			continue
+		}
+
+		require.NoError(t, err, test.description)
+		assert.Equal(t, test.want, val, test.description)
+	}
+}
+
+func TestInitFlags_AliasErrors(t *testing.T) {
+	t.Parallel()
+
+	var a, b string
+	bindings := []FlagBinding{
+		{Name: "a", Target: &a, Aliases: []string{"b"}},
+		{Name: "b", Target: &b, Aliases: []string{""}},
+	}
+
+	cmd := cobra.Command{Use: "app"}
+	err := InitFlags(NewViper("al"), cmd.Flags(), bindings)
+	assert.ErrorIs(t, err, ErrDuplicateName)
+	assert.ErrorIs(t, err, ErrEmptyName)
+}
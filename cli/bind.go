@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// BindOption sets an optional field of the FlagBinding returned by Bind.
+type BindOption func(binding *FlagBinding)
+
+// Bind returns a FlagBinding of a target and a default of the same type T, which is checked by the
+// compiler instead of InitFlags. T can be any type supported by InitFlags.
+//
+//	var port int
+//	bindings := []cli.FlagBinding{
+//		cli.Bind("port", &port, 8080, "port to listen on", cli.WithShorthand('p'), cli.WithRange(1, 65535)),
+//	}
+func Bind[T any](name string, target *T, def T, usage string, opts ...BindOption) FlagBinding {
+	binding := FlagBinding{
+		Usage:   usage,
+		Name:    name,
+		Target:  target,
+		Default: def,
+	}
+
+	for _, opt := range opts {
+		opt(&binding)
+	}
+
+	return binding
+}
+
+// WithShorthand sets the shorthand of a flag.
+func WithShorthand(shorthand rune) BindOption {
+	return func(binding *FlagBinding) {
+		binding.Shorthand = shorthand
+	}
+}
+
+// WithSecret marks a flag as a secret, see FlagBinding.Secret.
+func WithSecret() BindOption {
+	return func(binding *FlagBinding) {
+		binding.Secret = true
+	}
+}
+
+// WithRequired requires a flag to be set by a flag, an env variable or a config file.
+func WithRequired() BindOption {
+	return func(binding *FlagBinding) {
+		binding.Required = true
+	}
+}
+
+// WithAliases sets the old names of a renamed flag, see FlagBinding.Aliases.
+func WithAliases(aliases ...string) BindOption {
+	return func(binding *FlagBinding) {
+		binding.Aliases = append(binding.Aliases, aliases...)
+	}
+}
+
+// WithDeprecated marks a flag as deprecated with a message, see FlagBinding.Deprecated.
+func WithDeprecated(msg string) BindOption {
+	return func(binding *FlagBinding) {
+		binding.Deprecated = msg
+	}
+}
+
+// WithCompletion sets the shell completion hints of a flag.
+func WithCompletion(completion Completion) BindOption {
+	return func(binding *FlagBinding) {
+		binding.Completion = completion
+	}
+}
+
+// WithPattern sets the regular expression a string, or every item of a string slice, must match.
+func WithPattern(pattern string) BindOption {
+	return func(binding *FlagBinding) {
+		binding.Pattern = pattern
+	}
+}
+
+// WithEnum sets the allowed values of a flag, or of every item of a slice flag.
+func WithEnum[T any](values ...T) BindOption {
+	return func(binding *FlagBinding) {
+		for _, val := range values {
+			binding.Enum = append(binding.Enum, val)
+		}
+	}
+}
+
+// WithRange sets the minimum and maximum of a number flag, or the minimum and maximum length of a
+// string, slice or map flag.
+func WithRange[N Number](minVal, maxVal N) BindOption {
+	return func(binding *FlagBinding) {
+		binding.Min = minVal
+		binding.Max = maxVal
+	}
+}
+
+// WithValidate sets a custom validation of the value of a flag of type T. The type of the target must
+// be assignable to T eg. the same type, which is checked by InitFlags. A value isn't converted, so a
+// validation of an int doesn't apply to an int64 flag.
+func WithValidate[T any](fn func(val T) error) BindOption {
+	return func(binding *FlagBinding) {
+		typ := reflect.TypeOf((*T)(nil)).Elem()
+
+		binding.validateType = typ
+		binding.Validate = func(val any) error {
+			rv := reflect.ValueOf(val)
+			if !rv.IsValid() || !rv.Type().AssignableTo(typ) {
+				return fmt.Errorf("value of type %T, expected %s: %w", val, typ, ErrInvalidRule)
+			}
+
+			// The value is assignable but not necessarily identical to T.
+			typed := reflect.New(typ).Elem()
+			typed.Set(rv)
+
+			return fn(typed.Interface().(T))
+		}
+	}
+}
+
+// Number is the constraint of the types of a minimum and a maximum.
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 |
+		~float32 | ~float64
+}
@@ -0,0 +1,172 @@
+package cli_test
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kylelemons/godebug/pretty"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/cybersamx/golib/cli"
+)
+
+func TestBind(t *testing.T) {
+	type vars struct {
+		port     int
+		timeout  time.Duration
+		tags     []string
+		labels   map[string]string
+		ip       net.IP
+		key      HexBytes
+		level    Level
+		addr     netip.Addr
+		password Secret
+	}
+
+	var got vars
+	bindings := []FlagBinding{
+		Bind("port", &got.port, 8080, "port", WithShorthand('p'), WithRange(1, 65535)),
+		Bind("timeout", &got.timeout, time.Second, "timeout", WithRange(time.Millisecond, time.Minute)),
+		Bind("tags", &got.tags, []string{"a"}, "tags", WithEnum("a", "b", "c")),
+		Bind("labels", &got.labels, map[string]string{"k": "v"}, "labels"),
+		Bind("ip", &got.ip, net.IPv4(127, 0, 0, 1), "ip"),
+		Bind("key", &got.key, HexBytes{0xca, 0xfe}, "key"),
+		Bind("level", &got.level, Info, "level", WithEnum(Warn, Info), WithAliases("log-level")),
+		Bind("addr", &got.addr, netip.MustParseAddr("::1"), "addr"),
+		Bind("password", &got.password, "", "password", WithRequired()),
+	}
+
+	v := NewViper("bind")
+	cmd := cobra.Command{Run: func(cmd *cobra.Command, args []string) {}}
+	err := InitFlags(v, cmd.Flags(), bindings)
+	require.NoError(t, err)
+
+	cmd.SetArgs([]string{"-p", "9090", "--tags", "b,c", "--log-level", "warn", "--password", "secret"})
+	require.NoError(t, cmd.Execute())
+
+	want := vars{
+		port:     9090,
+		timeout:  time.Second,
+		tags:     []string{"b", "c"},
+		labels:   map[string]string{"k": "v"},
+		ip:       net.IPv4(127, 0, 0, 1),
+		key:      HexBytes{0xca, 0xfe},
+		level:    Warn,
+		addr:     netip.MustParseAddr("::1"),
+		password: "secret",
+	}
+
+	diff := pretty.Compare(want, got)
+	assert.Emptyf(t, diff, "want: %+v, got: %+v", want, got)
+
+	err = ValidateFlags(v, cmd.Flags(), bindings)
+	assert.NoError(t, err)
+}
+
+func TestBind_Validation(t *testing.T) {
+	t.Parallel()
+
+	errOdd := errors.New("must be even")
+
+	var (
+		port int
+		name string
+	)
+
+	bindings := []FlagBinding{
+		Bind("port", &port, 0, "port", WithRange(1, 65535), WithValidate(func(val int) error {
+			if val%2 != 0 {
+				return errOdd
+			}
+
+			return nil
+		})),
+		Bind("name", &name, "", "name", WithRequired(), WithPattern(`^[a-z]+$`)),
+	}
+
+	v := NewViper("bind")
+	cmd := cobra.Command{Run: func(cmd *cobra.Command, args []string) {}}
+	err := InitFlags(v, cmd.Flags(), bindings)
+	require.NoError(t, err)
+
+	cmd.SetArgs([]string{"--port", "65537"})
+	require.NoError(t, cmd.Execute())
+
+	err = ValidateFlags(v, cmd.Flags(), bindings)
+	assert.ErrorIs(t, err, ErrOutOfRange)
+	assert.ErrorIs(t, err, errOdd)
+	assert.ErrorIs(t, err, ErrRequired)
+	assert.ErrorIs(t, err, ErrPatternMismatch)
+
+	// A custom validation of the wrong type is an invalid rule.
+	binding := Bind("name", &name, "", "name", WithValidate(func(val int) error { return nil }))
+	err = binding.Validate(name)
+	assert.ErrorIs(t, err, ErrInvalidRule)
+	assert.True(t, strings.Contains(err.Error(), "expected int"))
+}
+
+func TestBind_TypedRules(t *testing.T) {
+	t.Parallel()
+
+	var (
+		n64   int64
+		sizes []uint
+		delay time.Duration
+	)
+
+	// The allowed values are converted to the type of the target.
+	bindings := []FlagBinding{
+		Bind("n", &n64, int64(1), "n", WithEnum(1, 2), WithValidate(func(val int64) error {
+			if val > 1 {
+				return ErrOutOfRange
+			}
+
+			return nil
+		})),
+		Bind("sizes", &sizes, nil, "sizes", WithEnum(1, 2, 4)),
+		Bind("delay", &delay, time.Second, "delay", WithEnum("1s", "1m")),
+	}
+
+	v := NewViper("bind")
+	cmd := cobra.Command{Run: func(cmd *cobra.Command, args []string) {}}
+	err := InitFlags(v, cmd.Flags(), bindings)
+	require.NoError(t, err)
+
+	cmd.SetArgs([]string{"--sizes=1,4"})
+	require.NoError(t, cmd.Execute())
+	assert.NoError(t, ValidateFlags(v, cmd.Flags(), bindings))
+
+	cmd.SetArgs([]string{"--n=2", "--sizes=3", "--delay=2s"})
+	require.NoError(t, cmd.Execute())
+
+	err = ValidateFlags(v, cmd.Flags(), bindings)
+	var verrs ValidationErrors
+	require.ErrorAs(t, err, &verrs)
+	require.Len(t, verrs, 3)
+	assert.ErrorIs(t, verrs[0].Err, ErrOutOfRange)
+	assert.ErrorIs(t, verrs[1].Err, ErrNotInEnum)
+	assert.ErrorIs(t, verrs[2].Err, ErrNotInEnum)
+
+	// A rule that can't apply to the type of the target is found by InitFlags.
+	bindings = []FlagBinding{
+		Bind("n", &n64, 0, "n", WithEnum("one")),
+		Bind("delay", &delay, 0, "delay", WithValidate(func(val string) error { return nil })),
+		// A number isn't narrowed eg. an int64 of 300 to an int8 of 44.
+		Bind("n8", &n64, 0, "n8", WithValidate(func(val int8) error { return nil })),
+	}
+
+	err = InitFlags(NewViper("bind"), &pflag.FlagSet{}, bindings)
+	var berrs BindingErrors
+	require.ErrorAs(t, err, &berrs)
+	require.Len(t, berrs, 3)
+	for _, berr := range berrs {
+		assert.ErrorIs(t, berr, ErrInvalidRule)
+	}
+}
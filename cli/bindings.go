@@ -1,16 +1,12 @@
 package cli
 
 import (
-	"encoding/csv"
-	"encoding/json"
 	"errors"
-	"fmt"
 	"reflect"
 	"strings"
-	"time"
+	"sync"
 
 	"github.com/cybersamx/golib/stringsutils"
-	"github.com/spf13/cast"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
@@ -21,11 +17,6 @@ const (
 
 var ErrFlagBinding = errors.New("failed to bind flag")
 
-func flagBindingError(flagName string, err error) error {
-	return fmt.Errorf("flagBindingError - flag=%s; root_err=%v; %w",
-		flagName, err, ErrFlagBinding)
-}
-
 type FlagBindingParser func(v *viper.Viper, flags *pflag.FlagSet, binding *FlagBinding) error
 
 // FlagBinding represents the info for simplify the setup of the popular command line management
@@ -34,6 +25,9 @@ type FlagBindingParser func(v *viper.Viper, flags *pflag.FlagSet, binding *FlagB
 //  2. Define the default value of a flag.
 //  3. Once a flag is set by the user, where to bind the value of a flag to a target (variable or a
 //     field in a struct object).
+//  4. Optionally, the rules the resolved value must satisfy, which are checked by ValidateFlags.
+//
+// Use Bind to create a FlagBinding whose target and default types are checked by the compiler.
 type FlagBinding struct {
 	Usage     string
 	Name      string
@@ -41,23 +35,124 @@ type FlagBinding struct {
 	Target    any
 	Default   any
 	Parser    FlagBindingParser
+	Secret    bool // Mask the value in the usage, reports and errors, and allow reading it from a file.
+
+	// Aliases are the old names of a renamed flag, which still set the target as a flag, env variable
+	// or config key but are hidden from the help and log a deprecation warning, see SetLogger.
+	Aliases []string
+	// Deprecated is the deprecation message of the flag, which is hidden from the help and logs a
+	// warning with the message when it's set.
+	Deprecated string
+
+	Completion Completion // Values suggested by the shell completion, see RegisterCompletions.
+
+	Required bool                // The value must be set by a flag, an env variable or a config file.
+	Enum     []any               // Allowed values, every item is checked for a slice.
+	Min      any                 // Minimum of a number, or the minimum length of a string, slice or map.
+	Max      any                 // Maximum of a number, or the maximum length of a string, slice or map.
+	Pattern  string              // Regular expression a string (or every item of a string slice) must match.
+	Validate func(val any) error // Custom validation of the value.
+
+	validateType reflect.Type // Type of the value expected by a validation set with WithValidate.
+}
+
+// Substitute STORE.DB-URL to STORE_DB_URL
+var envReplacer = strings.NewReplacer(".", envDelimiter, "-", envDelimiter)
+
+// viperState is the state kept along with the config of a Viper instance, which viper has no room for:
+// the env prefix, the config files loaded by LoadConfig, the profile applied by ApplyProfile, the
+// sources added by AddSource and the warnings already logged.
+type viperState struct {
+	envPrefix string
+
+	mu            sync.Mutex // Guards the fields below.
+	configFiles   []string
+	activeProfile string
+	sources       []sourceEntry   // Sorted by level.
+	warned        map[string]bool // Warnings logged once even if the bindings are resolved again.
+	listKeys      map[string]bool // Keys of the slice bindings, which can be set in indexed env variables.
+}
+
+// states holds the state of every Viper instance used with the package.
+var states sync.Map
+
+// stateOf returns the state of a Viper instance, created by NewViper or on first use.
+func stateOf(v *viper.Viper) *viperState {
+	if state, ok := states.Load(v); ok {
+		return state.(*viperState)
+	}
+
+	state, _ := states.LoadOrStore(v, &viperState{})
+
+	return state.(*viperState)
 }
 
 func NewViper(envPrefix string) *viper.Viper {
 	v := viper.New()
 	v.SetEnvPrefix(envPrefix)
 	v.AutomaticEnv()
-
-	// Substitute STORE.DB-URL to STORE_DB_URL
-	envReplacer := strings.NewReplacer(".", envDelimiter, "-", envDelimiter)
 	v.SetEnvKeyReplacer(envReplacer)
 
+	states.Store(v, &viperState{envPrefix: envPrefix})
+
 	return v
 }
 
+// envPrefixOf returns the env prefix of a Viper instance created by NewViper.
+func envPrefixOf(v *viper.Viper) string {
+	return stateOf(v).envPrefix
+}
+
+// envVarName returns the name of the env variable that sets a key, eg. GL_STORE_DB_URL for the key
+// store.db-url and the prefix GL.
+func envVarName(envPrefix, key string) string {
+	name := key
+	if envPrefix != "" {
+		name = envPrefix + envDelimiter + key
+	}
+
+	return strings.ToUpper(envReplacer.Replace(name))
+}
+
 // InitFlags sets up the accepted flags in a command line program and then bind the values set in the
 // flags by the user to the target variable or field in a struct object.
+//
+// A target can be a pointer to any type supported by spf13/pflag: string, bool, int, int8, int16,
+// int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, time.Duration, net.IP,
+// net.IPNet, []byte (base64), HexBytes, []string, []bool, []int, []int32, []int64, []uint,
+// []float32, []float64, []time.Duration, []net.IP, map[string]string, map[string]int and
+// map[string]int64. A target of any other type is supported if it implements pflag.Value or
+// encoding.TextUnmarshaler, where env and config values are decoded with UnmarshalText (or Set if
+// the type only implements pflag.Value). Use a Parser for any other type.
+//
+// A secret binding, see FlagBinding.Secret and Secret, can also be set with the path of a file
+// containing the value with the flag, env variable or config key suffixed with -file eg.
+// --db-password-file for --db-password. The references in the value of a secret are expanded like any
+// other value, see below, so use $${ for a literal ${ in a secret eg. a password, and the errors of the
+// references in a secret don't include the value.
+//
+// A slice set in an env variable is encoded in csv format a,"b,c" or as a json array ["a", "b,c"], or
+// set item by item in indexed env variables eg. APP_TAGS_0=a and APP_TAGS_1=b,c for the flag tags. A
+// map set in an env variable is encoded in k1=v1,k2=v2 format, where a backslash escapes a comma or an
+// equal sign in a key or a value eg. k\,1=v\,1, or as a json object {"k1": "v1", "k2": "v2"}.
+//
+// The references in a value set in an env variable or a config file are expanded: ${ENV_VAR} to the
+// value of an env variable (in upper case), ${other.key} to the value of another key, and
+// ${ENV_VAR:-default} or ${other.key:-default} to the default if the env variable or the key isn't set
+// or is empty. Use $${ for a literal ${. A reference that can't be resolved or a cycle of references
+// is reported in BindingErrors, matching ErrUnresolvedReference or ErrReferenceCycle.
+//
+// The bindings are validated before any flag is set up. If any binding is invalid, InitFlags returns
+// BindingErrors listing every invalid binding. If a value set in an env variable or a config file
+// can't be decoded, the default value is kept and InitFlags returns BindingErrors listing every such
+// value, which match ErrDecodeValue with errors.Is.
 func InitFlags(v *viper.Viper, flags *pflag.FlagSet, bindings []FlagBinding) error {
+	if err := checkBindings(flags, bindings); err != nil {
+		return err
+	}
+
+	var errs BindingErrors
+
 	for _, binding := range bindings {
 		if binding.Parser != nil {
 			if err := binding.Parser(v, flags, &binding); err != nil {
@@ -67,159 +162,57 @@ func InitFlags(v *viper.Viper, flags *pflag.FlagSet, bindings []FlagBinding) err
 			continue
 		}
 
-		val := v.Get(binding.Name)
-		shorthand := stringsutils.RuneToString(binding.Shorthand)
-
-		switch target := binding.Target.(type) {
-		case *string:
-			var def string
-			if binding.Default != nil {
-				// No ok checking needed, if it fails, the zero value will be assigned.
-				def = binding.Default.(string)
-			}
-
-			flags.StringVarP(target, binding.Name, shorthand, def, binding.Usage)
-			if err := v.BindPFlag(binding.Name, flags.Lookup(binding.Name)); err != nil {
-				return flagBindingError(binding.Name, err)
-			}
-
-			if val != nil {
-				if str, err := cast.ToStringE(val); err == nil {
-					*target = str
-				}
-			}
-		case *bool:
-			var def bool
-			if binding.Default != nil {
-				def = binding.Default.(bool)
-			}
+		ft, ok := flagTypeOf(binding.Target)
+		if !ok {
+			return flagBindingError(binding.Name, ErrUnsupportedTarget)
+		}
 
-			flags.BoolVarP(target, binding.Name, shorthand, def, binding.Usage)
-			if err := v.BindPFlag(binding.Name, flags.Lookup(binding.Name)); err != nil {
-				return flagBindingError(binding.Name, err)
-			}
+		if isListTarget(binding.Target) {
+			setListKeys(v, append([]string{binding.Name}, binding.Aliases...))
+		}
 
-			if val != nil {
-				if b, err := cast.ToBoolE(val); err == nil {
-					*target = b
-				}
-			}
-		case *int:
-			var def int
-			if binding.Default != nil {
-				def = binding.Default.(int)
-			}
+		key, origin, err := bindingKey(v, flags, &binding)
+		if err != nil {
+			errs = append(errs, &BindingError{Name: binding.Name, Err: err})
+		}
 
-			flags.IntVarP(target, binding.Name, shorthand, def, binding.Usage)
-			if err := v.BindPFlag(binding.Name, flags.Lookup(binding.Name)); err != nil {
-				return flagBindingError(binding.Name, err)
-			}
+		val, err := lookupBindingValue(v, &binding, key)
+		if err != nil {
+			errs = append(errs, &BindingError{Name: binding.Name, Err: err})
+		}
 
-			if val != nil {
-				if i, err := cast.ToIntE(val); err == nil {
-					*target = i
-				}
-			}
-		case *time.Duration:
-			var def time.Duration
-			if binding.Default != nil {
-				def = binding.Default.(time.Duration)
-			}
-			flags.DurationVarP(target, binding.Name, shorthand, def, binding.Usage)
-			if err := v.BindPFlag(binding.Name, flags.Lookup(binding.Name)); err != nil {
-				return flagBindingError(binding.Name, err)
-			}
+		shorthand := stringsutils.RuneToString(binding.Shorthand)
 
-			if val != nil {
-				if t, err := cast.ToDurationE(val); err == nil {
-					*target = t
-				}
-			}
-		case *[]string:
-			var def []string
-			if binding.Default != nil {
-				def = binding.Default.([]string)
-			}
+		ft.define(flags, binding.Target, binding.Name, shorthand, binding.Default, binding.Usage)
+		if err := v.BindPFlag(binding.Name, flags.Lookup(binding.Name)); err != nil {
+			return flagBindingError(binding.Name, err)
+		}
 
-			flags.StringSliceVarP(target, binding.Name, shorthand, def, binding.Usage)
-			if err := v.BindPFlag(binding.Name, flags.Lookup(binding.Name)); err != nil {
-				return flagBindingError(binding.Name, err)
-			}
+		defineAliases(v, flags, ft, &binding)
+		warnDeprecated(v, &binding, key, origin)
 
-			// An environment variable, unlike a flag, is a singleton. Any subsequent set env will just override
-			// the previous value. So the set string value should be encoded and the following section decodes
-			// an env value. For env, viper returns a string (not []string) so we can decode the string.
-			// For 1 item slice, the value can be encoded in key=value.
-			// For 1+ item slice, the value must be encoded in csv item1,item2,item3 format.
-			// We may have comma in the value as long as it is enclosed by "" - standard csv.
-			// For a map[string]string typed flag, the flag value must be formatted as key=value only.
-			if val != nil {
-				s, err := cast.ToStringSliceE(val)
-				if err != nil || len(s) == 0 {
-					break
-				}
-
-				if len(s) == 1 {
-					// If the env variable is set, we get an 1-item slice. Treat the string
-					// as a csv and parse accordingly.
-					sreader := strings.NewReader(s[0])
-					creader := csv.NewReader(sreader)
-					if valSlice, err := creader.Read(); err == nil {
-						s = valSlice
-					}
-				}
-
-				*target = s
-			}
-		case *map[string]string:
-			var def map[string]string
-			if binding.Default != nil {
-				def = binding.Default.(map[string]string)
+		// A value set in an env variable or a config file overrides the default value. A value set in
+		// the flag will in turn override the target when the flags are parsed. If the value can't be
+		// decoded, the default value is kept and the error is returned once all the flags are set up.
+		if val != nil {
+			if err := setTarget(binding.Target, val); err != nil {
+				errs = append(errs, &BindingError{
+					Name: binding.Name,
+					Err:  decodeError(v, flags, &binding, key, val, err),
+				})
 			}
+		}
 
-			flags.StringToStringVarP(target, binding.Name, shorthand, def, binding.Usage)
-			if err := v.BindPFlag(binding.Name, flags.Lookup(binding.Name)); err != nil {
+		if isSecret(&binding) {
+			if err := bindSecret(v, flags, &binding, key, origin); err != nil {
 				return flagBindingError(binding.Name, err)
 			}
-
-			if val != nil {
-				m, err := cast.ToStringMapStringE(val)
-				if err == nil {
-					*target = m
-					break
-				}
-
-				// An environment variable, unlike a flag, is a singleton. Any subsequent set env will just override
-				// the previous value. So the set string value should be encoded and the following section decodes
-				// an env value. For env, viper returns a string (not map[string]string) so we can decode the string.
-				// For 1 key map, the value can be encoded in key=value or json {"key": "value"} format.
-				// For 1+ key map, the value must be encoded in json {"key": "value"} format.
-				str, err := cast.ToStringE(val)
-				if err != nil {
-					break
-				}
-
-				segments := strings.Split(str, "=")
-				if len(segments) >= 2 {
-					var valMap map[string]string
-					if err := json.Unmarshal([]byte(segments[1]), &valMap); err == nil {
-						*target = valMap
-						break
-					}
-
-					valMap = map[string]string{segments[0]: segments[1]}
-					*target = valMap
-				}
-			}
-		default:
-			if target == nil {
-				break
-			}
-
-			typ := reflect.TypeOf(target).String()
-			panic(fmt.Sprintf("need to implement logic to bind flag to type %s", typ))
 		}
 	}
 
+	if len(errs) > 0 {
+		return errs
+	}
+
 	return nil
 }
@@ -0,0 +1,207 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// ConfigFlag is the name of the persistent flag (and env variable and config key) of the root command
+// that sets the path of the config file.
+const ConfigFlag = "config"
+
+// Command declares a cobra command, the bindings of its flags and its subcommands. Use NewCommand
+// to build the cobra command tree.
+type Command struct {
+	Use     string
+	Aliases []string
+	Short   string
+	Long    string
+	Example string
+	Args    cobra.PositionalArgs
+
+	// PersistentBindings are bound to the persistent flags of the command, which are inherited by the
+	// subcommands.
+	PersistentBindings []FlagBinding
+	// Bindings are bound to the local flags of the command.
+	Bindings []FlagBinding
+
+	// PersistentPreRunE is called after the bindings of the executing command are resolved. The hooks
+	// of all the commands from the root to the executing command are called in that order.
+	PersistentPreRunE func(cmd *cobra.Command, args []string) error
+	RunE              func(cmd *cobra.Command, args []string) error
+
+	Commands []*Command
+}
+
+// commandNode links a cobra command to its declaration and the node of its parent.
+type commandNode struct {
+	spec   *Command
+	parent *commandNode
+}
+
+// bindings returns the persistent bindings of the command and its ancestors and the local bindings of
+// the command, which are all the bindings in effect when the command is executed.
+func (n *commandNode) bindings() []FlagBinding {
+	var bindings []FlagBinding
+	for _, node := range n.path() {
+		bindings = append(bindings, node.spec.PersistentBindings...)
+	}
+
+	return append(bindings, n.spec.Bindings...)
+}
+
+// path returns the nodes from the root to n.
+func (n *commandNode) path() []*commandNode {
+	var nodes []*commandNode
+	for node := n; node != nil; node = node.parent {
+		nodes = append([]*commandNode{node}, nodes...)
+	}
+
+	return nodes
+}
+
+// NewCommand builds the cobra command tree declared by spec. The root command has the persistent flags
+// --config, which can also be set in the env variable eg. APP_CONFIG, to read a config file, and
+// --profile, see ApplyProfile. When a command is executed, its PersistentPreRunE reads the config file,
+// applies the profile, binds v to the flags of the executing command and resolves the bindings in
+// effect with the precedence of flag, env variable, config file and default, then validates them with
+// ValidateFlags. So the targets are set before RunE is called, and commands sharing a flag name don't
+// interfere with each other.
+//
+// It returns BindingErrors if any binding is invalid, including a local flag that conflicts with a
+// persistent flag of an ancestor.
+func NewCommand(v *viper.Viper, spec *Command) (*cobra.Command, error) {
+	nodes := make(map[*cobra.Command]*commandNode)
+
+	root, err := buildCommand(v, spec, nil, nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	root.PersistentFlags().String(ConfigFlag, "", "path to the config file")
+	setCompletionAnnotation(root.PersistentFlags().Lookup(ConfigFlag), cobra.BashCompFilenameExt, configExts)
+	root.PersistentFlags().String(ProfileFlag, "", "profile overlaid on the config eg. dev or prod")
+	for _, name := range []string{ConfigFlag, ProfileFlag} {
+		if err := v.BindPFlag(name, root.PersistentFlags().Lookup(name)); err != nil {
+			return nil, flagBindingError(name, err)
+		}
+	}
+
+	// Cobra only calls the PersistentPreRunE nearest to the executing command, so the root has the only
+	// hook and calls the hooks of the declarations.
+	root.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		node := nodes[cmd]
+		if node == nil {
+			return nil
+		}
+
+		if err := prepareCommand(v, cmd.Flags(), node.bindings()); err != nil {
+			return err
+		}
+
+		for _, n := range node.path() {
+			if n.spec.PersistentPreRunE != nil {
+				if err := n.spec.PersistentPreRunE(cmd, args); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	}
+
+	return root, nil
+}
+
+func buildCommand(v *viper.Viper, spec *Command, parent *commandNode, nodes map[*cobra.Command]*commandNode) (*cobra.Command, error) {
+	cmd := cobra.Command{
+		Use:     spec.Use,
+		Aliases: spec.Aliases,
+		Short:   spec.Short,
+		Long:    spec.Long,
+		Example: spec.Example,
+		Args:    spec.Args,
+		RunE:    spec.RunE,
+	}
+
+	node := commandNode{spec: spec, parent: parent}
+	nodes[&cmd] = &node
+
+	// Check the bindings in effect together to catch conflicts between the flags of different levels.
+	scratch := pflag.NewFlagSet(spec.Use, pflag.ContinueOnError)
+	scratch.String(ConfigFlag, "", "")
+	scratch.String(ProfileFlag, "", "")
+	if err := checkBindings(scratch, node.bindings()); err != nil {
+		return nil, err
+	}
+
+	if err := InitFlags(v, cmd.PersistentFlags(), spec.PersistentBindings); err != nil {
+		return nil, err
+	}
+
+	if err := InitFlags(v, cmd.Flags(), spec.Bindings); err != nil {
+		return nil, err
+	}
+
+	if err := RegisterCompletions(&cmd, spec.PersistentBindings); err != nil {
+		return nil, err
+	}
+
+	if err := RegisterCompletions(&cmd, spec.Bindings); err != nil {
+		return nil, err
+	}
+
+	for _, sub := range spec.Commands {
+		subCmd, err := buildCommand(v, sub, &node, nodes)
+		if err != nil {
+			return nil, err
+		}
+
+		cmd.AddCommand(subCmd)
+	}
+
+	return &cmd, nil
+}
+
+// prepareCommand reads the config file, applies the profile, binds v to the flags of the executing
+// command and resolves and validates the bindings.
+func prepareCommand(v *viper.Viper, flags *pflag.FlagSet, bindings []FlagBinding) error {
+	// The config file is merged on top of the config files loaded by LoadConfig, if any, once if the
+	// command is executed again.
+	if path := v.GetString(ConfigFlag); path != "" && !contains(ConfigFiles(v), path) {
+		if err := mergeConfigFile(v, path); err != nil {
+			return err
+		}
+	}
+
+	if err := ApplyProfile(v, ""); err != nil {
+		return err
+	}
+
+	// The bindings of v point to the flags of the command built last, rebind them to the flags of the
+	// executing command.
+	for i := range bindings {
+		binding := &bindings[i]
+		if binding.Parser != nil {
+			continue
+		}
+
+		names := []string{binding.Name}
+		if isSecret(binding) {
+			names = append(names, secretFileKey(binding.Name))
+		}
+
+		for _, name := range names {
+			if err := v.BindPFlag(name, flags.Lookup(name)); err != nil {
+				return flagBindingError(name, err)
+			}
+		}
+	}
+
+	if err := ResolveFlags(v, flags, bindings); err != nil {
+		return err
+	}
+
+	return ValidateFlags(v, flags, bindings)
+}
@@ -0,0 +1,190 @@
+package cli_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/cybersamx/golib/cli"
+)
+
+type commandVars struct {
+	dbURL      string
+	verbose    bool
+	servePort  int
+	migrateDir string
+	adminPort  int
+	ran        string
+	hooks      []string
+}
+
+func newCommandSpec(target *commandVars) *Command {
+	run := func(name string) func(cmd *cobra.Command, args []string) error {
+		return func(cmd *cobra.Command, args []string) error {
+			target.ran = name
+			return nil
+		}
+	}
+
+	hook := func(name string) func(cmd *cobra.Command, args []string) error {
+		return func(cmd *cobra.Command, args []string) error {
+			target.hooks = append(target.hooks, name)
+			return nil
+		}
+	}
+
+	return &Command{
+		Use: "app",
+		PersistentBindings: []FlagBinding{
+			{Name: "store.db-url", Target: &target.dbURL, Default: "postgres://localhost"},
+			{Name: "verbose", Shorthand: 'v', Target: &target.verbose},
+		},
+		PersistentPreRunE: hook("app"),
+		Commands: []*Command{
+			{
+				Use: "serve",
+				Bindings: []FlagBinding{
+					{Name: "port", Target: &target.servePort, Default: 8080, Min: 1},
+				},
+				PersistentPreRunE: hook("serve"),
+				RunE:              run("serve"),
+			},
+			{
+				Use: "migrate",
+				Bindings: []FlagBinding{
+					{Name: "dir", Target: &target.migrateDir, Default: "migrations"},
+					{Name: "port", Target: &target.adminPort, Default: 9090},
+				},
+				RunE: run("migrate"),
+			},
+		},
+	}
+}
+
+func TestNewCommand(t *testing.T) {
+	dir := t.TempDir()
+	cfgFile := filepath.Join(dir, "config.yaml")
+	content := "store:\n  db-url: postgres://file\nport: 7070\ndir: file-migrations\n"
+	require.NoError(t, os.WriteFile(cfgFile, []byte(content), 0o600))
+
+	tests := []struct {
+		name    string
+		args    []string
+		envs    map[string]string
+		want    commandVars
+		wantErr bool
+	}{
+		{
+			name: "Defaults",
+			args: []string{"serve"},
+			want: commandVars{dbURL: "postgres://localhost", servePort: 8080, ran: "serve", hooks: []string{"app", "serve"}},
+		},
+		{
+			name: "Flags",
+			args: []string{"serve", "--port", "8000", "-v", "--store.db-url", "postgres://flag"},
+			want: commandVars{dbURL: "postgres://flag", verbose: true, servePort: 8000, ran: "serve", hooks: []string{"app", "serve"}},
+		},
+		{
+			name: "Config file",
+			args: []string{"migrate", "--config", cfgFile},
+			want: commandVars{dbURL: "postgres://file", migrateDir: "file-migrations", adminPort: 7070, ran: "migrate", hooks: []string{"app"}},
+		},
+		{
+			name: "Config file in env",
+			args: []string{"serve", "--port", "8000"},
+			envs: map[string]string{"CMD_CONFIG": cfgFile, "CMD_VERBOSE": "true"},
+			want: commandVars{dbURL: "postgres://file", verbose: true, servePort: 8000, ran: "serve", hooks: []string{"app", "serve"}},
+		},
+		{
+			name:    "Invalid value",
+			args:    []string{"serve", "--port", "0"},
+			wantErr: true,
+		},
+		{
+			name:    "Missing config file",
+			args:    []string{"serve", "--config", filepath.Join(dir, "missing.yaml")},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			for key, val := range test.envs {
+				t.Setenv(key, val)
+			}
+
+			var got commandVars
+			cmd, err := NewCommand(NewViper("cmd"), newCommandSpec(&got))
+			require.NoError(t, err)
+
+			cmd.SetArgs(test.args)
+			cmd.SilenceUsage = true
+			cmd.SilenceErrors = true
+			err = cmd.Execute()
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+
+			// Only the targets of the executing command are resolved.
+			if got.ran == "serve" {
+				got.migrateDir, got.adminPort = "", 0
+			} else {
+				got.servePort = 0
+			}
+
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestNewCommand_ExecuteAgain(t *testing.T) {
+	cfgFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(cfgFile, []byte("port: 7070\n"), 0o600))
+
+	v := NewViper("cmd")
+
+	var got commandVars
+	cmd, err := NewCommand(v, newCommandSpec(&got))
+	require.NoError(t, err)
+
+	// The config file is merged once.
+	for i := 0; i < 2; i++ {
+		cmd.SetArgs([]string{"serve", "--config", cfgFile})
+		require.NoError(t, cmd.Execute())
+		assert.Equal(t, 7070, got.servePort)
+		assert.Equal(t, []string{cfgFile}, ConfigFiles(v))
+	}
+}
+
+func TestNewCommand_Conflict(t *testing.T) {
+	t.Parallel()
+
+	var verbose, local bool
+	spec := Command{
+		Use: "app",
+		PersistentBindings: []FlagBinding{
+			{Name: "verbose", Target: &verbose},
+		},
+		Commands: []*Command{
+			{Use: "sub", Bindings: []FlagBinding{{Name: "verbose", Target: &local}}},
+		},
+	}
+
+	_, err := NewCommand(NewViper("cmd"), &spec)
+	assert.ErrorIs(t, err, ErrDuplicateName)
+
+	spec = Command{
+		Use:      "app",
+		Bindings: []FlagBinding{{Name: ConfigFlag, Target: &local}},
+	}
+
+	_, err = NewCommand(NewViper("cmd"), &spec)
+	assert.ErrorIs(t, err, ErrDuplicateName)
+}
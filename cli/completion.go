@@ -0,0 +1,115 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// CompletionFunc returns the values suggested by the shell completion of a flag, see
+// cobra.Command.RegisterFlagCompletionFunc.
+type CompletionFunc func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective)
+
+// Completion sets the values suggested by the shell completion of a flag. Only one kind of hint is
+// used, in the order of Func, Values, Extensions and DirsOnly. The values of FlagBinding.Enum are
+// suggested if no hint is set.
+type Completion struct {
+	Func       CompletionFunc
+	Values     []string // Static values.
+	Extensions []string // Extensions of the suggested files eg. yaml, json.
+	DirsOnly   bool     // Suggest directories only.
+}
+
+// RegisterCompletions registers the completion hints of the bindings, already set up with InitFlags in
+// the local or persistent flags of cmd, for the completion scripts generated by cobra for bash, zsh,
+// fish and powershell. The file of a secret completes as a file path. NewCommand registers the
+// completions of every command.
+func RegisterCompletions(cmd *cobra.Command, bindings []FlagBinding) error {
+	var errs BindingErrors
+
+	for i := range bindings {
+		binding := &bindings[i]
+
+		if isSecret(binding) {
+			if flag := cmd.Flag(secretFileKey(binding.Name)); flag != nil {
+				setCompletionAnnotation(flag, cobra.BashCompFilenameExt, nil)
+			}
+
+			// The value of a secret isn't suggested.
+			continue
+		}
+
+		flag := cmd.Flag(binding.Name)
+		if flag == nil {
+			continue
+		}
+
+		if err := registerCompletion(cmd, flag, binding); err != nil {
+			errs = append(errs, &BindingError{Name: binding.Name, Err: err})
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func registerCompletion(cmd *cobra.Command, flag *pflag.Flag, binding *FlagBinding) error {
+	hint := binding.Completion
+
+	switch {
+	case hint.Func != nil:
+		return cmd.RegisterFlagCompletionFunc(flag.Name, hint.Func)
+	case len(hint.Values) > 0:
+		return cmd.RegisterFlagCompletionFunc(flag.Name, staticCompletion(hint.Values))
+	case len(hint.Extensions) > 0:
+		setCompletionAnnotation(flag, cobra.BashCompFilenameExt, hint.Extensions)
+	case hint.DirsOnly:
+		setCompletionAnnotation(flag, cobra.BashCompSubdirsInDir, nil)
+	case len(binding.Enum) > 0:
+		values := make([]string, 0, len(binding.Enum))
+		for _, item := range binding.Enum {
+			if text, ok := textOf(item); ok {
+				values = append(values, text)
+			} else {
+				values = append(values, fmt.Sprint(item))
+			}
+		}
+
+		return cmd.RegisterFlagCompletionFunc(flag.Name, staticCompletion(values))
+	}
+
+	return nil
+}
+
+// staticCompletion returns a CompletionFunc suggesting the values starting with the text to complete.
+func staticCompletion(values []string) CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		var matches []string
+		for _, val := range values {
+			if strings.HasPrefix(val, toComplete) {
+				matches = append(matches, val)
+			}
+		}
+
+		return matches, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// setCompletionAnnotation sets the annotation cobra reads to complete a flag with file or directory
+// paths, the same as cobra.Command.MarkFlagFilename and MarkFlagDirname.
+func setCompletionAnnotation(flag *pflag.Flag, key string, values []string) {
+	if flag.Annotations == nil {
+		flag.Annotations = map[string][]string{}
+	}
+
+	if values == nil {
+		values = []string{}
+	}
+
+	flag.Annotations[key] = values
+}
@@ -0,0 +1,117 @@
+package cli_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/cybersamx/golib/cli"
+)
+
+func TestRegisterCompletions(t *testing.T) {
+	var (
+		format   string
+		level    Level
+		input    string
+		outDir   string
+		region   string
+		password string
+	)
+
+	spec := Command{
+		Use: "app",
+		PersistentBindings: []FlagBinding{
+			{Name: "level", Target: &level, Enum: []any{Error, Warn, Info}},
+		},
+		Commands: []*Command{
+			{
+				Use: "convert",
+				Bindings: []FlagBinding{
+					{Name: "format", Target: &format, Completion: Completion{Values: []string{"json", "yaml", "toml"}}},
+					{Name: "input", Target: &input, Completion: Completion{Extensions: []string{"json", "yaml"}}},
+					{Name: "out-dir", Target: &outDir, Completion: Completion{DirsOnly: true}},
+					{
+						Name:   "region",
+						Target: &region,
+						Completion: Completion{
+							Func: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+								return []string{toComplete + "-east", toComplete + "-west"}, cobra.ShellCompDirectiveNoFileComp
+							},
+						},
+					},
+					{Name: "password", Target: &password, Secret: true},
+				},
+				RunE: func(cmd *cobra.Command, args []string) error { return nil },
+			},
+		},
+	}
+
+	tests := []struct {
+		description string
+		args        []string
+		want        []string
+	}{
+		{
+			description: "Static values",
+			args:        []string{"convert", "--format", ""},
+			want:        []string{"json", "yaml", "toml", ":4"},
+		},
+		{
+			description: "Static values with prefix",
+			args:        []string{"convert", "--format", "t"},
+			want:        []string{"toml", ":4"},
+		},
+		{
+			description: "Enum of a persistent flag",
+			args:        []string{"convert", "--level", ""},
+			want:        []string{"error", "warn", "info", ":4"},
+		},
+		{
+			description: "File extensions",
+			args:        []string{"convert", "--input", ""},
+			want:        []string{"json", "yaml", ":8"},
+		},
+		{
+			description: "Dirs only",
+			args:        []string{"convert", "--out-dir", ""},
+			want:        []string{":16"},
+		},
+		{
+			description: "Function",
+			args:        []string{"convert", "--region", "us"},
+			want:        []string{"us-east", "us-west", ":4"},
+		},
+		{
+			description: "Secret file",
+			args:        []string{"convert", "--password-file", ""},
+			want:        []string{":0"},
+		},
+		{
+			description: "Config file",
+			args:        []string{"convert", "--config", ""},
+			want:        []string{"yaml", "yml", "json", "toml", ":8"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			cmd, err := NewCommand(NewViper("cmp"), &spec)
+			require.NoError(t, err)
+
+			var buf bytes.Buffer
+			cmd.SetOut(&buf)
+			cmd.SetErr(io.Discard)
+			cmd.SetArgs(append([]string{cobra.ShellCompRequestCmd}, test.args...))
+			require.NoError(t, cmd.Execute())
+
+			// The output ends with the directive and a help message.
+			lines := strings.Split(buf.String(), "\n")
+			assert.Equal(t, test.want, lines[:len(test.want)])
+		})
+	}
+}
@@ -0,0 +1,258 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/cybersamx/golib/system"
+	"github.com/spf13/viper"
+)
+
+const (
+	defaultConfigName = "config"
+	fragmentDir       = "conf.d"
+)
+
+var ErrEmptyAppName = errors.New("app name must not be empty")
+
+// Extensions of the config files looked up in a directory, in the order of preference.
+var configExts = []string{"yaml", "yml", "json", "toml"}
+
+// Extensions of the fragments in a conf.d directory.
+var fragmentExts = []string{"yaml", "yml"}
+
+// ConfigLayers sets where LoadConfig looks up the config files of an app. Only App is required.
+type ConfigLayers struct {
+	App       string // Name of the app eg. myapp.
+	Name      string // Base name of the config files in the system and user dirs, config by default.
+	SystemDir string // /etc/<app> by default.
+	UserDir   string // $XDG_CONFIG_HOME/<app> by default, or ~/.config/<app> if XDG_CONFIG_HOME isn't set.
+	WorkDir   string // Dir the project config file is looked up from, the work dir by default.
+	File      string // Explicit config file eg. set with --config, must exist if set.
+}
+
+// LoadConfig reads the config files of the layers into v, merged in this order so that a value in a
+// layer overrides the value of the same key in the layers before:
+//  1. System: <SystemDir>/<Name>.yaml followed by the fragments <SystemDir>/conf.d/*.yaml.
+//  2. User: <UserDir>/<Name>.yaml followed by the fragments <UserDir>/conf.d/*.yaml.
+//  3. Project: the first .<App>.yaml found walking up from WorkDir to the root dir.
+//  4. Explicit: File.
+//
+// A config file can also have the extension yml, json or toml, and the fragments of a conf.d dir are
+// merged in the lexical order of their names. Nested keys are merged key by key, while any other value
+// including a list replaces the value of the layers before. A missing file is skipped, except File.
+//
+// LoadConfig returns the paths of the loaded files in the order of merging, which is also reported by
+// EffectiveConfig. ConfigFileUsed of v returns the last loaded file.
+func LoadConfig(v *viper.Viper, layers ConfigLayers) ([]string, error) {
+	if layers.App == "" {
+		return nil, ErrEmptyAppName
+	}
+
+	var files []string
+
+	for _, dir := range []string{systemConfigDir(&layers), userConfigDir(&layers)} {
+		if dir == "" {
+			continue
+		}
+
+		if file := findConfigFile(dir, layers.name()); file != "" {
+			files = append(files, file)
+		}
+
+		fragments, err := findFragments(filepath.Join(dir, fragmentDir))
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, fragments...)
+	}
+
+	if file := findProjectConfigFile(&layers); file != "" {
+		files = append(files, file)
+	}
+
+	if layers.File != "" {
+		files = append(files, layers.File)
+	}
+
+	if err := readConfigFiles(v, files); err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// ConfigFiles returns the config files loaded into v by LoadConfig and NewCommand in the order of
+// merging.
+func ConfigFiles(v *viper.Viper) []string {
+	state := stateOf(v)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	return append([]string(nil), state.configFiles...)
+}
+
+func setConfigFiles(v *viper.Viper, files []string) {
+	state := stateOf(v)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	state.configFiles = files
+}
+
+func (l *ConfigLayers) name() string {
+	if l.Name == "" {
+		return defaultConfigName
+	}
+
+	return l.Name
+}
+
+func systemConfigDir(layers *ConfigLayers) string {
+	if layers.SystemDir != "" {
+		return layers.SystemDir
+	}
+
+	return filepath.Join("/etc", layers.App)
+}
+
+func userConfigDir(layers *ConfigLayers) string {
+	if layers.UserDir != "" {
+		return layers.UserDir
+	}
+
+	// os.UserConfigDir follows XDG_CONFIG_HOME on unix but not on macOS, which is expected of a cli.
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, layers.App)
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".config", layers.App)
+}
+
+// findConfigFile returns the path of the config file with a base name in a dir, or an empty string if
+// there is none.
+func findConfigFile(dir, name string) string {
+	for _, ext := range configExts {
+		path := filepath.Join(dir, name+"."+ext)
+		if isFile(path) {
+			return path
+		}
+	}
+
+	return ""
+}
+
+// findProjectConfigFile walks up from the work dir and returns the first project config file found.
+func findProjectConfigFile(layers *ConfigLayers) string {
+	dir := layers.WorkDir
+	if dir == "" {
+		dir = system.WorkDir()
+	}
+
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+
+	for {
+		if file := findConfigFile(dir, "."+layers.App); file != "" {
+			return file
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+
+		dir = parent
+	}
+}
+
+// findFragments returns the fragments in a conf.d dir sorted by name.
+func findFragments(dir string) ([]string, error) {
+	var fragments []string
+
+	for _, ext := range fragmentExts {
+		matches, err := filepath.Glob(filepath.Join(dir, "*."+ext))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, match := range matches {
+			if isFile(match) {
+				fragments = append(fragments, match)
+			}
+		}
+	}
+
+	sort.Strings(fragments)
+
+	return fragments, nil
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// readConfigFiles replaces the config of v with the config files merged in order.
+func readConfigFiles(v *viper.Viper, files []string) error {
+	for i, file := range files {
+		v.SetConfigFile(file)
+
+		read := v.MergeInConfig
+		if i == 0 {
+			read = v.ReadInConfig
+		}
+
+		if err := read(); err != nil {
+			return fmt.Errorf("failed to read config file %s: %w", file, err)
+		}
+	}
+
+	setConfigFiles(v, files)
+
+	return nil
+}
+
+// mergeConfigFile merges a config file into the config of v.
+func mergeConfigFile(v *viper.Viper, file string) error {
+	v.SetConfigFile(file)
+	if err := v.MergeInConfig(); err != nil {
+		return fmt.Errorf("failed to read config file %s: %w", file, err)
+	}
+
+	setConfigFiles(v, append(ConfigFiles(v), file))
+
+	return nil
+}
+
+// reloadConfig reads the config files loaded into v again, or the config file set in v if the files
+// aren't loaded by LoadConfig or NewCommand, and overlays the section of the active profile.
+func reloadConfig(v *viper.Viper) error {
+	var err error
+	if files := ConfigFiles(v); len(files) > 0 {
+		err = readConfigFiles(v, files)
+	} else {
+		err = v.ReadInConfig()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if profile := ActiveProfile(v); profile != "" {
+		_, err = mergeProfileSection(v, profile)
+	}
+
+	return err
+}
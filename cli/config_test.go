@@ -0,0 +1,97 @@
+package cli_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/cybersamx/golib/cli"
+)
+
+func writeFile(t *testing.T, path, content string) string {
+	t.Helper()
+
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o700))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	systemFile := writeFile(t, filepath.Join(dir, "etc", "config.yaml"),
+		"name: system\nstore:\n  host: system-host\n  port: 5432\ntags: [a, b]\n")
+	fragment1 := writeFile(t, filepath.Join(dir, "etc", "conf.d", "10-store.yaml"), "store:\n  port: 6432\n")
+	fragment2 := writeFile(t, filepath.Join(dir, "etc", "conf.d", "20-tags.yml"), "tags: [c]\n")
+	writeFile(t, filepath.Join(dir, "etc", "conf.d", "README.md"), "not a fragment")
+	userFile := writeFile(t, filepath.Join(dir, "xdg", "cfgtest", "config.json"), `{"name": "user"}`)
+	projectFile := writeFile(t, filepath.Join(dir, "project", ".cfgtest.toml"), "[store]\nhost = \"project-host\"\n")
+	workDir := filepath.Join(dir, "project", "sub", "dir")
+	require.NoError(t, os.MkdirAll(workDir, 0o700))
+	explicitFile := writeFile(t, filepath.Join(dir, "explicit.yaml"), "level: debug\n")
+
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "xdg"))
+
+	v := NewViper("cfgtest")
+	files, err := LoadConfig(v, ConfigLayers{
+		App:       "cfgtest",
+		SystemDir: filepath.Join(dir, "etc"),
+		WorkDir:   workDir,
+		File:      explicitFile,
+	})
+	require.NoError(t, err)
+
+	want := []string{systemFile, fragment1, fragment2, userFile, projectFile, explicitFile}
+	assert.Equal(t, want, files)
+	assert.Equal(t, want, ConfigFiles(v))
+	assert.Equal(t, explicitFile, v.ConfigFileUsed())
+
+	assert.Equal(t, "user", v.GetString("name"))
+	assert.Equal(t, "project-host", v.GetString("store.host"))
+	assert.Equal(t, 6432, v.GetInt("store.port"))
+	assert.Equal(t, []string{"c"}, v.GetStringSlice("tags"))
+	assert.Equal(t, "debug", v.GetString("level"))
+
+	var level string
+	flags := pflag.NewFlagSet("cfgtest", pflag.ContinueOnError)
+	err = InitFlags(v, flags, []FlagBinding{{Name: "level", Target: &level}})
+	require.NoError(t, err)
+	assert.Equal(t, "debug", level)
+
+	var buf bytes.Buffer
+	report := EffectiveConfig(v, flags, nil)
+	assert.Equal(t, want, report.ConfigFiles)
+	require.NoError(t, report.WriteTable(&buf))
+	assert.Contains(t, buf.String(), "Config files:\n  "+systemFile+"\n")
+}
+
+func TestLoadConfig_Errors(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	v := NewViper("cfgtest")
+
+	_, err := LoadConfig(v, ConfigLayers{})
+	assert.ErrorIs(t, err, ErrEmptyAppName)
+
+	// The explicit file must exist.
+	_, err = LoadConfig(v, ConfigLayers{
+		App:       "cfgtest",
+		SystemDir: dir,
+		UserDir:   dir,
+		WorkDir:   dir,
+		File:      filepath.Join(dir, "missing.yaml"),
+	})
+	assert.Error(t, err)
+
+	// Nothing to load.
+	files, err := LoadConfig(v, ConfigLayers{App: "cfgtest", SystemDir: dir, UserDir: dir, WorkDir: dir})
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+}
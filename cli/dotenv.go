@@ -0,0 +1,176 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+var ErrDotEnvSyntax = errors.New("invalid .env line")
+
+// DotEnvSource is a Source of the variables in a .env file, named like the env variables of the keys
+// eg. APP_DB_HOST for the key db.host with the env prefix APP. The variables aren't set in the
+// environment, so the values of the env variables can take precedence over the file.
+type DotEnvSource struct {
+	path      string
+	envPrefix string
+
+	mu   sync.RWMutex // Guards vars and err.
+	vars map[string]string
+	err  error // Error reading the file again after a change.
+}
+
+// NewDotEnvSource returns a DotEnvSource of the variables in a .env file. A line of the file is either
+// empty, a comment starting with # or a variable NAME=value, optionally preceded by export. A value can
+// be quoted in double quotes, in which \n, \r, \t, \" and \\ are escaped, or in single quotes, in which
+// nothing is escaped. An unquoted value ends before a # preceded by a space. It returns ErrDotEnvSyntax
+// if a line is invalid.
+func NewDotEnvSource(path, envPrefix string) (*DotEnvSource, error) {
+	vars, err := readDotEnv(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DotEnvSource{path: path, envPrefix: envPrefix, vars: vars}, nil
+}
+
+// Get returns the value of the variable of a key. It returns the error of reading the file again if it
+// is changed into an invalid file.
+func (s *DotEnvSource) Get(key string) (any, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.err != nil {
+		return nil, false, s.err
+	}
+
+	val, ok := s.vars[envVarName(s.envPrefix, key)]
+	if !ok {
+		return nil, false, nil
+	}
+
+	return val, true, nil
+}
+
+// Watch reads the file again and calls onChange when the file is changed, until ctx is done.
+func (s *DotEnvSource) Watch(ctx context.Context, onChange func()) error {
+	file := filepath.Clean(s.path)
+
+	return watchDir(ctx, filepath.Dir(file), func(event fsnotify.Event) bool {
+		return filepath.Clean(event.Name) == file
+	}, func() {
+		vars, err := readDotEnv(file)
+
+		s.mu.Lock()
+		if err == nil {
+			s.vars = vars
+		}
+		s.err = err
+		s.mu.Unlock()
+
+		onChange()
+	})
+}
+
+func readDotEnv(path string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	vars, err := parseDotEnv(content)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", path, err)
+	}
+
+	return vars, nil
+}
+
+// parseDotEnv returns the variables of the content of a .env file.
+func parseDotEnv(content []byte) (map[string]string, error) {
+	vars := make(map[string]string)
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		line = strings.TrimPrefix(line, "export ")
+
+		name, val, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !ok || !envRefPattern.MatchString(strings.ToUpper(name)) {
+			return nil, fmt.Errorf("%d: %w", n, ErrDotEnvSyntax)
+		}
+
+		val, err := dotEnvValue(strings.TrimSpace(val))
+		if err != nil {
+			return nil, fmt.Errorf("%d: %w: %w", n, ErrDotEnvSyntax, err)
+		}
+
+		vars[name] = val
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return vars, nil
+}
+
+// dotEnvEscaper unescapes a value in double quotes.
+var dotEnvEscaper = strings.NewReplacer(`\n`, "\n", `\r`, "\r", `\t`, "\t", `\"`, `"`, `\\`, `\`)
+
+// dotEnvValue returns the value of a variable from the text after the =.
+func dotEnvValue(text string) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+
+	quote := text[0]
+	if quote != '"' && quote != '\'' {
+		if i := strings.Index(text, " #"); i >= 0 {
+			text = text[:i]
+		}
+
+		return strings.TrimSpace(text), nil
+	}
+
+	end := -1
+	for i := 1; i < len(text); i++ {
+		if quote == '"' && text[i] == '\\' {
+			i++
+			continue
+		}
+
+		if text[i] == quote {
+			end = i
+			break
+		}
+	}
+
+	if end < 0 {
+		return "", errors.New("unterminated quote")
+	}
+
+	if rest := strings.TrimSpace(text[end+1:]); rest != "" && !strings.HasPrefix(rest, "#") {
+		return "", fmt.Errorf("unexpected %q after the quote", rest)
+	}
+
+	val := text[1:end]
+	if quote == '"' {
+		val = dotEnvEscaper.Replace(val)
+	}
+
+	return val, nil
+}
@@ -0,0 +1,148 @@
+package cli_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/cybersamx/golib/cli"
+)
+
+func TestNewDotEnvSource(t *testing.T) {
+	t.Parallel()
+
+	content := `# Database
+DOT_DB_HOST=db.local
+export DOT_DB_PORT = 5432 # port
+DOT_NAME="John \"Doe\"\tJr # not a comment"
+DOT_PATH='C:\dir'  # comment
+DOT_EMPTY=
+DOT_TAGS=a,b
+`
+	path := filepath.Join(t.TempDir(), ".env")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	source, err := NewDotEnvSource(path, "DOT")
+	require.NoError(t, err)
+
+	tests := []struct {
+		key    string
+		want   any
+		wantOk bool
+	}{
+		{key: "db.host", want: "db.local", wantOk: true},
+		{key: "db-port", want: "5432", wantOk: true},
+		{key: "name", want: "John \"Doe\"\tJr # not a comment", wantOk: true},
+		{key: "path", want: `C:\dir`, wantOk: true},
+		{key: "empty", want: "", wantOk: true},
+		{key: "missing"},
+	}
+
+	for _, tt := range tests {
+		val, ok, err := source.Get(tt.key)
+		require.NoError(t, err)
+		assert.Equal(t, tt.wantOk, ok, tt.key)
+		assert.Equal(t, tt.want, val, tt.key)
+	}
+
+	v := NewViper("DOT")
+	require.NoError(t, AddSource(v, source, OriginDefault))
+
+	var tags []string
+	err = InitFlags(v, pflag.NewFlagSet("test", pflag.ContinueOnError), []FlagBinding{
+		Bind("tags", &tags, nil, "tags"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, tags)
+}
+
+func TestNewDotEnvSource_Invalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "No equal sign", content: "A=1\nINVALID\n", wantErr: ":2: invalid .env line"},
+		{name: "Invalid name", content: "1A=1\n", wantErr: ":1: invalid .env line"},
+		{name: "Unterminated quote", content: `A="value`, wantErr: ":1: invalid .env line: unterminated quote"},
+		{name: "Text after quote", content: `A="value" b`, wantErr: `:1: invalid .env line: unexpected "b" after the quote`},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), ".env")
+			require.NoError(t, os.WriteFile(path, []byte(tt.content), 0o600))
+
+			_, err := NewDotEnvSource(path, "")
+			assert.ErrorIs(t, err, ErrDotEnvSyntax)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+
+	_, err := NewDotEnvSource(filepath.Join(t.TempDir(), ".env"), "")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestDotEnvSource_Watch(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), ".env")
+	require.NoError(t, os.WriteFile(path, []byte("NAME=old\n"), 0o600))
+
+	source, err := NewDotEnvSource(path, "")
+	require.NoError(t, err)
+
+	changed := make(chan struct{}, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err = source.Watch(ctx, func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+	require.NoError(t, err)
+
+	wait := func() {
+		t.Helper()
+
+		select {
+		case <-changed:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the change")
+		}
+	}
+
+	// Replace the file atomically so the source doesn't read a truncated file.
+	replace := func(content string) {
+		tmpPath := filepath.Join(filepath.Dir(path), "env.tmp")
+		require.NoError(t, os.WriteFile(tmpPath, []byte(content), 0o600))
+		require.NoError(t, os.Rename(tmpPath, path))
+	}
+
+	replace("NAME=new\n")
+	wait()
+
+	val, ok, err := source.Get("name")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "new", val)
+
+	replace("NAME\n")
+	wait()
+
+	_, _, err = source.Get("name")
+	assert.ErrorIs(t, err, ErrDotEnvSyntax)
+}
@@ -0,0 +1,280 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+var ErrUnknownEnv = errors.New("unknown env variable")
+
+// EnvVar describes an env variable accepted by a program.
+type EnvVar struct {
+	Name    string `json:"name"`
+	Key     string `json:"key"` // The flag name and config key set by the env variable.
+	Type    string `json:"type"`
+	Default string `json:"default"` // Default formatted as a flag value, empty for secrets.
+	Usage   string `json:"usage"`
+}
+
+// UnknownEnvError lists the env variables with the env prefix that don't map to any binding.
+type UnknownEnvError struct {
+	Names []string
+}
+
+func (e *UnknownEnvError) Error() string {
+	return fmt.Sprintf("unknownEnvError - env=%s; %v", strings.Join(e.Names, ","), ErrUnknownEnv)
+}
+
+func (e *UnknownEnvError) Unwrap() error {
+	return ErrUnknownEnv
+}
+
+// EnvVars returns the env variables accepted by the bindings, eg. APP_STORE_DB_URL for the binding
+// store.db-url and the prefix APP, in the order of the bindings. A secret binding also accepts the
+// env variable of the path of the file containing the secret eg. APP_DB_PASSWORD_FILE.
+func EnvVars(envPrefix string, bindings []FlagBinding) []EnvVar {
+	vars := make([]EnvVar, 0, len(bindings))
+
+	for i := range bindings {
+		binding := &bindings[i]
+		ev := EnvVar{
+			Name:  envVarName(envPrefix, binding.Name),
+			Key:   binding.Name,
+			Usage: binding.Usage,
+		}
+
+		// Set up the flag in a scratch FlagSet with a new target to get the type and the formatted
+		// default without touching the target of the binding.
+		if ft, ok := flagTypeOf(binding.Target); ok && binding.Parser == nil {
+			flags := pflag.NewFlagSet("env", pflag.ContinueOnError)
+			target := reflect.New(reflect.TypeOf(binding.Target).Elem()).Interface()
+			ft.define(flags, target, binding.Name, "", binding.Default, binding.Usage)
+
+			flag := flags.Lookup(binding.Name)
+			ev.Type = flag.Value.Type()
+			ev.Default = flag.DefValue
+		} else if binding.Default != nil {
+			ev.Default = fmt.Sprint(binding.Default)
+		}
+
+		if isSecret(binding) {
+			ev.Default = ""
+		}
+
+		vars = append(vars, ev)
+
+		if isSecret(binding) {
+			fileKey := secretFileKey(binding.Name)
+			vars = append(vars, EnvVar{
+				Name:  envVarName(envPrefix, fileKey),
+				Key:   fileKey,
+				Type:  "file",
+				Usage: fmt.Sprintf("path to a file containing the value of %s", ev.Name),
+			})
+		}
+	}
+
+	return vars
+}
+
+// WriteEnvVars writes the env variables as a table eg. for the help of a command.
+func WriteEnvVars(w io.Writer, vars []EnvVar) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ENV\tTYPE\tDEFAULT\tDESCRIPTION")
+	for _, ev := range vars {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", ev.Name, ev.Type, ev.Default, ev.Usage)
+	}
+
+	return tw.Flush()
+}
+
+// CheckEnv returns UnknownEnvError if the environment contains variables with the env prefix, eg.
+// APP_* for the prefix APP, that don't map to any binding, usually a typo. Names in allowed are
+// accepted as well eg. for env variables read by the program without a binding. Use it as a strict
+// mode before InitFlags.
+func CheckEnv(envPrefix string, bindings []FlagBinding, allowed ...string) error {
+	if envPrefix == "" {
+		return nil
+	}
+
+	known := make(map[string]bool, len(bindings)+len(allowed))
+	for _, ev := range EnvVars(envPrefix, bindings) {
+		known[ev.Name] = true
+	}
+
+	// The indexed env variables eg. APP_TAGS_0 are only accepted for a slice binding.
+	lists := make(map[string]bool)
+	for _, binding := range bindings {
+		names := append([]string{binding.Name}, binding.Aliases...)
+		for _, name := range names[1:] {
+			known[envVarName(envPrefix, name)] = true
+		}
+
+		if isListTarget(binding.Target) && binding.Parser == nil {
+			for _, name := range names {
+				lists[envVarName(envPrefix, name)] = true
+			}
+		}
+	}
+
+	// The env variables of the flags set up by NewCommand.
+	for _, name := range []string{ConfigFlag, ProfileFlag} {
+		known[envVarName(envPrefix, name)] = true
+	}
+
+	for _, name := range allowed {
+		known[name] = true
+	}
+
+	prefix := strings.ToUpper(envPrefix) + envDelimiter
+
+	var unknown []string
+	for _, env := range os.Environ() {
+		name, _, _ := strings.Cut(env, "=")
+		if strings.HasPrefix(name, prefix) && !known[name] && !lists[indexedBase(name)] {
+			unknown = append(unknown, name)
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return &UnknownEnvError{Names: unknown}
+	}
+
+	return nil
+}
+
+// indexedBase returns the name of the env variable of an indexed env variable eg. APP_TAGS for
+// APP_TAGS_0, or an empty string if name isn't indexed.
+func indexedBase(name string) string {
+	i := strings.LastIndex(name, envDelimiter)
+	if i < 0 {
+		return ""
+	}
+
+	if _, err := strconv.ParseUint(name[i+len(envDelimiter):], 10, 0); err != nil {
+		return ""
+	}
+
+	return name[:i]
+}
+
+// isListTarget returns true if a target is a slice, but not a byte slice, which can be set in indexed
+// env variables.
+func isListTarget(target any) bool {
+	typ := reflect.TypeOf(target)
+	if typ == nil || typ.Kind() != reflect.Pointer {
+		return false
+	}
+
+	typ = typ.Elem()
+
+	return typ.Kind() == reflect.Slice && typ.Elem().Kind() != reflect.Uint8
+}
+
+// setListKeys records the keys of a slice binding, whose values are read from the indexed env variables.
+func setListKeys(v *viper.Viper, keys []string) {
+	state := stateOf(v)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if state.listKeys == nil {
+		state.listKeys = make(map[string]bool)
+	}
+
+	for _, key := range keys {
+		state.listKeys[strings.ToLower(key)] = true
+	}
+}
+
+// isListKey returns true if a key is the name or an alias of a slice binding.
+func isListKey(v *viper.Viper, key string) bool {
+	state := stateOf(v)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	return state.listKeys[strings.ToLower(key)]
+}
+
+// indexedEnv returns the values of the indexed env variables of a key eg. APP_TAGS_0, APP_TAGS_1 for
+// the key tags of a slice binding, up to the first missing index, if the env variable of the key isn't
+// set.
+func indexedEnv(v *viper.Viper, key string) ([]string, bool) {
+	name := envVarName(envPrefixOf(v), key)
+	if val, ok := os.LookupEnv(name); (ok && val != "") || !isListKey(v, key) {
+		return nil, false
+	}
+
+	var vals []string
+	for i := 0; ; i++ {
+		val, ok := os.LookupEnv(indexedEnvName(name, i))
+		if !ok {
+			break
+		}
+
+		vals = append(vals, val)
+	}
+
+	return vals, len(vals) > 0
+}
+
+func indexedEnvName(name string, i int) string {
+	return name + envDelimiter + strconv.Itoa(i)
+}
+
+// indexedEnvItem returns the name of the indexed env variable of a key, and its value in vals, that
+// can't be decoded into an item of target. It returns false if the value of the key isn't set in the
+// indexed env variables.
+func indexedEnvItem(v *viper.Viper, target any, key string, vals any) (string, string, bool) {
+	items, ok := vals.([]string)
+	if _, indexed := indexedEnv(v, key); !ok || !indexed {
+		return "", "", false
+	}
+
+	for i, item := range items {
+		if _, err := decodeValue(target, []string{item}); err != nil {
+			return indexedEnvName(envVarName(envPrefixOf(v), key), i), item, true
+		}
+	}
+
+	return "", "", false
+}
+
+// isEnvSet returns true if the value of a key is set in its env variable or, for a slice binding, in
+// its indexed env variables. Viper ignores an empty env variable by default.
+func isEnvSet(v *viper.Viper, key string) bool {
+	if val, ok := os.LookupEnv(envVarName(envPrefixOf(v), key)); ok && val != "" {
+		return true
+	}
+
+	_, ok := indexedEnv(v, key)
+
+	return ok
+}
+
+// rawValue returns the value of a key from v, or the values of the indexed env variables of the key as
+// a list if the env variable of the key isn't set, without expanding the references, see lookupValue.
+// Call it when the value isn't set in a flag, as the indexed env variables take precedence over the
+// config file but not over the flags. The value of a source added by AddSource takes precedence at its
+// level.
+func rawValue(v *viper.Viper, key string) (any, error) {
+	if val, ok, err := sourceValue(v, key); ok || err != nil {
+		return val, err
+	}
+
+	if vals, ok := indexedEnv(v, key); ok {
+		return vals, nil
+	}
+
+	return v.Get(key), nil
+}
@@ -0,0 +1,96 @@
+package cli_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kylelemons/godebug/pretty"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/cybersamx/golib/cli"
+)
+
+func TestEnvVars(t *testing.T) {
+	t.Parallel()
+
+	var (
+		url      string
+		timeout  = time.Minute
+		tags     []string
+		level    Level
+		password string
+	)
+
+	bindings := []FlagBinding{
+		{Usage: "database url", Name: "store.db-url", Target: &url, Default: "postgres://localhost"},
+		{Usage: "timeout", Name: "timeout", Target: &timeout, Default: time.Second},
+		{Usage: "tags", Name: "tags", Target: &tags},
+		{Usage: "log level", Name: "level", Target: &level, Default: Warn},
+		{Usage: "password", Name: "password", Target: &password, Default: "secret", Secret: true},
+	}
+
+	want := []EnvVar{
+		{Name: "APP_STORE_DB_URL", Key: "store.db-url", Type: "string", Default: "postgres://localhost", Usage: "database url"},
+		{Name: "APP_TIMEOUT", Key: "timeout", Type: "duration", Default: "1s", Usage: "timeout"},
+		{Name: "APP_TAGS", Key: "tags", Type: "stringSlice", Default: "[]", Usage: "tags"},
+		{Name: "APP_LEVEL", Key: "level", Type: "log-level", Default: "warn", Usage: "log level"},
+		{Name: "APP_PASSWORD", Key: "password", Type: "string", Usage: "password"},
+		{Name: "APP_PASSWORD_FILE", Key: "password-file", Type: "file", Usage: "path to a file containing the value of APP_PASSWORD"},
+	}
+
+	got := EnvVars("app", bindings)
+	diff := pretty.Compare(want, got)
+	assert.Empty(t, diff)
+
+	// The targets are untouched.
+	assert.Equal(t, time.Minute, timeout)
+	assert.Empty(t, url)
+
+	var buf bytes.Buffer
+	err := WriteEnvVars(&buf, got)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(buf.String(), "ENV"))
+	assert.Contains(t, buf.String(), "APP_STORE_DB_URL")
+}
+
+func TestCheckEnv(t *testing.T) {
+	var (
+		url      string
+		tags     []string
+		password string
+	)
+
+	bindings := []FlagBinding{
+		{Name: "store.db-url", Target: &url},
+		{Name: "tags", Target: &tags},
+		{Name: "password", Target: &password, Secret: true},
+	}
+
+	t.Setenv("CHK_STORE_DB_URL", "postgres://localhost")
+	t.Setenv("CHK_PASSWORD_FILE", "/run/secrets/password")
+	t.Setenv("CHK_TAGS_0", "a")
+	t.Setenv("CHK_TAGS_1", "b")
+	t.Setenv("CHK_HOME", "/home/app")
+	t.Setenv("CHK_CONFIG", "/etc/chk/config.yaml")
+	t.Setenv("CHK_PROFILE", "prod")
+	err := CheckEnv("chk", bindings, "CHK_HOME")
+	assert.NoError(t, err)
+
+	t.Setenv("CHK_STORE_DB_UR", "postgres://localhost")
+	t.Setenv("CHK_PASWORD", "secret")
+	// Only a slice binding accepts indexed env variables.
+	t.Setenv("CHK_STORE_DB_URL_0", "postgres://localhost")
+	err = CheckEnv("chk", bindings)
+	require.ErrorIs(t, err, ErrUnknownEnv)
+
+	var envErr *UnknownEnvError
+	require.ErrorAs(t, err, &envErr)
+	assert.Equal(t, []string{"CHK_HOME", "CHK_PASWORD", "CHK_STORE_DB_UR", "CHK_STORE_DB_URL_0"}, envErr.Names)
+
+	// No prefix, no check.
+	err = CheckEnv("", bindings)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,178 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cybersamx/golib/stringsutils"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+var (
+	ErrEmptyName          = errors.New("flag name must not be empty")
+	ErrNilTarget          = errors.New("target must be a non-nil pointer")
+	ErrUnsupportedTarget  = errors.New("unsupported target type")
+	ErrDefaultType        = errors.New("default is not assignable to the target type")
+	ErrInvalidShorthand   = errors.New("shorthand must be one ASCII character")
+	ErrDuplicateName      = errors.New("duplicate flag name")
+	ErrDuplicateShorthand = errors.New("duplicate flag shorthand")
+	ErrDecodeValue        = errors.New("unable to decode value")
+)
+
+// BindingError is the error of a single FlagBinding. It matches ErrFlagBinding and the cause Err with
+// errors.Is.
+type BindingError struct {
+	Name string
+	Err  error
+}
+
+func (e *BindingError) Error() string {
+	return fmt.Sprintf("flagBindingError - flag=%s; root_err=%v; %v", e.Name, e.Err, ErrFlagBinding)
+}
+
+func (e *BindingError) Unwrap() []error {
+	return []error{e.Err, ErrFlagBinding}
+}
+
+// BindingErrors lists the errors of every invalid FlagBinding passed to InitFlags.
+type BindingErrors []*BindingError
+
+func (e BindingErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+
+	return strings.Join(msgs, "\n")
+}
+
+func (e BindingErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, err := range e {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
+
+func flagBindingError(flagName string, err error) error {
+	return &BindingError{Name: flagName, Err: err}
+}
+
+// checkBindings validates the bindings before any flag is defined in flags, which panics when a flag
+// is redefined or has an invalid shorthand. It returns BindingErrors listing every invalid binding.
+func checkBindings(flags *pflag.FlagSet, bindings []FlagBinding) error {
+	var errs BindingErrors
+
+	names := map[string]bool{}
+	shorthands := map[string]bool{}
+
+	for _, binding := range bindings {
+		addErr := func(err error) {
+			errs = append(errs, &BindingError{Name: binding.Name, Err: err})
+		}
+
+		if binding.Name == "" {
+			addErr(fmt.Errorf("flag with usage %q: %w", binding.Usage, ErrEmptyName))
+		} else if names[binding.Name] || flags.Lookup(binding.Name) != nil {
+			addErr(ErrDuplicateName)
+		}
+
+		names[binding.Name] = true
+
+		for _, alias := range binding.Aliases {
+			switch {
+			case alias == "":
+				addErr(fmt.Errorf("alias: %w", ErrEmptyName))
+			case names[alias] || flags.Lookup(alias) != nil:
+				addErr(fmt.Errorf("alias %s: %w", alias, ErrDuplicateName))
+			}
+
+			names[alias] = true
+		}
+
+		if isSecret(&binding) {
+			fileKey := secretFileKey(binding.Name)
+			if names[fileKey] || flags.Lookup(fileKey) != nil {
+				addErr(fmt.Errorf("flag %s of the secret file: %w", fileKey, ErrDuplicateName))
+			}
+
+			names[fileKey] = true
+		}
+
+		if binding.Shorthand != 0 {
+			shorthand := stringsutils.RuneToString(binding.Shorthand)
+
+			switch {
+			case binding.Shorthand >= utf8.RuneSelf:
+				addErr(fmt.Errorf("shorthand %s: %w", shorthand, ErrInvalidShorthand))
+			case shorthands[shorthand] || flags.ShorthandLookup(shorthand) != nil:
+				addErr(fmt.Errorf("shorthand %s: %w", shorthand, ErrDuplicateShorthand))
+			}
+
+			shorthands[shorthand] = true
+		}
+
+		// A custom parser takes care of the target and the default.
+		if binding.Parser != nil {
+			continue
+		}
+
+		rv := reflect.ValueOf(binding.Target)
+		if rv.Kind() != reflect.Pointer || rv.IsNil() {
+			addErr(fmt.Errorf("target %T: %w", binding.Target, ErrNilTarget))
+			continue
+		}
+
+		if _, ok := flagTypeOf(binding.Target); !ok {
+			addErr(fmt.Errorf("target %T: %w", binding.Target, ErrUnsupportedTarget))
+			continue
+		}
+
+		if binding.Default != nil && !reflect.TypeOf(binding.Default).AssignableTo(rv.Type().Elem()) {
+			addErr(fmt.Errorf("default %T for target %T: %w", binding.Default, binding.Target, ErrDefaultType))
+		}
+
+		if _, err := enumValues(&binding); err != nil {
+			addErr(err)
+		}
+
+		if typ := binding.validateType; typ != nil && !rv.Type().Elem().AssignableTo(typ) {
+			addErr(fmt.Errorf("validation of %s for target %T: %w", typ, binding.Target, ErrInvalidRule))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// decodeError returns the error of a value set under the name or an alias of a binding that can't be
+// decoded to the type of the target, with the source of the value.
+func decodeError(v *viper.Viper, flags *pflag.FlagSet, binding *FlagBinding, key string, val any, err error) error {
+	var source string
+	switch origin := valueOrigin(v, flags, key); origin {
+	case OriginEnv:
+		source = "env " + envVarName(envPrefixOf(v), key)
+		if name, item, ok := indexedEnvItem(v, binding.Target, key, val); ok {
+			source, val = "env "+name, item
+		}
+	case OriginFile:
+		source = "config file"
+	default:
+		source = origin.String()
+	}
+
+	str := fmt.Sprint(val)
+	if isSecret(binding) {
+		str = Secret(str).String()
+	}
+
+	return fmt.Errorf("%w %q from %s: %w", ErrDecodeValue, str, source, err)
+}
@@ -0,0 +1,85 @@
+package cli_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/cybersamx/golib/cli"
+)
+
+func TestInitFlags_InvalidBindings(t *testing.T) {
+	t.Parallel()
+
+	var (
+		str      string
+		number   int
+		nilPtr   *string
+		custom   struct{}
+		bytesHex HexBytes
+	)
+
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.StringP("existing", "e", "", "existing flag")
+
+	bindings := []FlagBinding{
+		{Name: "", Target: &str},
+		{Name: "nil-target", Target: nil},
+		{Name: "nil-ptr", Target: nilPtr},
+		{Name: "non-ptr", Target: str},
+		{Name: "unsupported", Target: &custom},
+		{Name: "default-type", Target: &number, Default: "123"},
+		{Name: "shorthand", Shorthand: 'ß', Target: &str},
+		{Name: "existing", Target: &str},
+		{Name: "str", Target: &str},
+		{Name: "str", Target: &str},
+		{Name: "short-existing", Shorthand: 'e', Target: &number},
+		{Name: "bytes-hex", Target: &bytesHex, Default: []byte("assignable")},
+	}
+
+	err := InitFlags(nil, flags, bindings)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrFlagBinding)
+
+	var bindingErrs BindingErrors
+	require.True(t, errors.As(err, &bindingErrs))
+
+	want := map[string]error{
+		"":               ErrEmptyName,
+		"nil-target":     ErrNilTarget,
+		"nil-ptr":        ErrNilTarget,
+		"non-ptr":        ErrNilTarget,
+		"unsupported":    ErrUnsupportedTarget,
+		"default-type":   ErrDefaultType,
+		"shorthand":      ErrInvalidShorthand,
+		"existing":       ErrDuplicateName,
+		"str":            ErrDuplicateName,
+		"short-existing": ErrDuplicateShorthand,
+	}
+
+	require.Len(t, bindingErrs, len(want))
+	for _, bindingErr := range bindingErrs {
+		assert.ErrorIs(t, bindingErr, want[bindingErr.Name], bindingErr.Name)
+	}
+
+	// No flag should be set up when any binding is invalid.
+	assert.Nil(t, flags.Lookup("bytes-hex"))
+}
+
+func TestInitFlags_DuplicateBindings(t *testing.T) {
+	t.Parallel()
+
+	var str1, str2 string
+
+	bindings := []FlagBinding{
+		{Name: "str1", Shorthand: 's', Target: &str1},
+		{Name: "str2", Shorthand: 's', Target: &str2},
+	}
+
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	err := InitFlags(NewViper("GL"), flags, bindings)
+	assert.ErrorIs(t, err, ErrDuplicateShorthand)
+}
@@ -0,0 +1,260 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+const (
+	refStart     = "${"
+	refEnd       = '}'
+	refEscape    = "$${"
+	refDefaultOp = ":-"
+)
+
+var (
+	ErrUnresolvedReference = errors.New("unresolved reference")
+	ErrReferenceCycle      = errors.New("reference cycle")
+)
+
+// A reference to an env variable is in upper case, a reference to a config key isn't.
+var envRefPattern = regexp.MustCompile(`^[A-Z_][A-Z0-9_]*$`)
+
+// lookupValue returns the value of a key like rawValue, with the references in the value, or in the
+// items of a list or a map, expanded:
+//   - ${ENV_VAR} is the value of an env variable, whose name is in upper case.
+//   - ${other.key} is the value of another key, which may have references too.
+//   - ${ENV_VAR:-default} or ${other.key:-default} is the default if the env variable or key isn't
+//     set or is empty. The default may have references too.
+//   - $${ is a literal ${.
+//
+// A value that is only a reference to a key, eg. ${other.key}, is the value of the key as is, eg. a list,
+// instead of a string. It returns ErrUnresolvedReference if a reference can't be resolved, and
+// ErrReferenceCycle if a key references itself through other keys.
+func lookupValue(v *viper.Viper, key string) (any, error) {
+	raw, err := rawValue(v, key)
+	if err != nil {
+		return nil, err
+	}
+
+	in := interpolator{v: v, stack: []string{strings.ToLower(key)}}
+
+	return in.expandValue(raw)
+}
+
+// lookupBindingValue returns the value of a binding under key like lookupValue. The error of a secret
+// doesn't include any part of the value, eg. the name of an unresolved reference.
+func lookupBindingValue(v *viper.Viper, binding *FlagBinding, key string) (any, error) {
+	val, err := lookupValue(v, key)
+	if err == nil || !isSecret(binding) {
+		return val, err
+	}
+
+	for _, target := range []error{ErrUnresolvedReference, ErrReferenceCycle} {
+		if errors.Is(err, target) {
+			return nil, fmt.Errorf("%w in the value %s of %s", target, maskedValue, key)
+		}
+	}
+
+	return nil, err
+}
+
+// lookupString returns the value of a key as a string with the references expanded.
+func lookupString(v *viper.Viper, key string) (string, error) {
+	val, err := lookupValue(v, key)
+	if err != nil {
+		return "", err
+	}
+
+	return cast.ToStringE(val)
+}
+
+// interpolator expands the references in the values of v.
+type interpolator struct {
+	v     *viper.Viper
+	stack []string // Keys being expanded, to detect cycles.
+}
+
+func (in *interpolator) expandValue(val any) (any, error) {
+	switch typed := val.(type) {
+	case string:
+		if ref, ok := wholeRef(typed); ok {
+			return in.resolve(ref)
+		}
+
+		return in.expandString(typed)
+	case []string:
+		items := make([]string, 0, len(typed))
+		for _, item := range typed {
+			expanded, err := in.expandString(item)
+			if err != nil {
+				return nil, err
+			}
+
+			items = append(items, expanded)
+		}
+
+		return items, nil
+	case []any:
+		items := make([]any, 0, len(typed))
+		for _, item := range typed {
+			expanded, err := in.expandValue(item)
+			if err != nil {
+				return nil, err
+			}
+
+			items = append(items, expanded)
+		}
+
+		return items, nil
+	case map[string]any:
+		m := make(map[string]any, len(typed))
+		for k, item := range typed {
+			expanded, err := in.expandValue(item)
+			if err != nil {
+				return nil, err
+			}
+
+			m[k] = expanded
+		}
+
+		return m, nil
+	}
+
+	return val, nil
+}
+
+func (in *interpolator) expandString(str string) (string, error) {
+	if !strings.Contains(str, refStart) {
+		return str, nil
+	}
+
+	var sb strings.Builder
+
+	for i := 0; i < len(str); {
+		switch {
+		case strings.HasPrefix(str[i:], refEscape):
+			sb.WriteString(refStart)
+			i += len(refEscape)
+		case strings.HasPrefix(str[i:], refStart):
+			end := refEndIndex(str, i)
+			if end < 0 {
+				return "", fmt.Errorf("unterminated reference at offset %d: %w", i, ErrUnresolvedReference)
+			}
+
+			val, err := in.resolve(str[i+len(refStart) : end])
+			if err != nil {
+				return "", err
+			}
+
+			sb.WriteString(refText(val))
+			i = end + 1
+		default:
+			sb.WriteByte(str[i])
+			i++
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// resolve returns the value of a reference, without the enclosing ${ and }.
+func (in *interpolator) resolve(ref string) (any, error) {
+	name, def, hasDef := strings.Cut(ref, refDefaultOp)
+
+	var (
+		val any
+		err error
+	)
+
+	if envRefPattern.MatchString(name) {
+		if env, ok := os.LookupEnv(name); ok {
+			val = env
+		}
+	} else {
+		val, err = in.resolveKey(name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if hasDef && (val == nil || val == "") {
+		return in.expandString(def)
+	}
+
+	if val == nil {
+		return nil, fmt.Errorf("%s%s%c: %w", refStart, ref, refEnd, ErrUnresolvedReference)
+	}
+
+	return val, nil
+}
+
+// resolveKey returns the expanded value of a key, or nil if the key isn't set.
+func (in *interpolator) resolveKey(key string) (any, error) {
+	key = strings.ToLower(key)
+	for _, k := range in.stack {
+		if k == key {
+			chain := append(append([]string(nil), in.stack...), key)
+			return nil, fmt.Errorf("%s: %w", strings.Join(chain, " -> "), ErrReferenceCycle)
+		}
+	}
+
+	in.stack = append(in.stack, key)
+	defer func() { in.stack = in.stack[:len(in.stack)-1] }()
+
+	raw, err := rawValue(in.v, key)
+	if err != nil {
+		return nil, err
+	}
+
+	return in.expandValue(raw)
+}
+
+// wholeRef returns the reference of a string that is only a reference eg. ${other.key}.
+func wholeRef(str string) (string, bool) {
+	if !strings.HasPrefix(str, refStart) || refEndIndex(str, 0) != len(str)-1 {
+		return "", false
+	}
+
+	return str[len(refStart) : len(str)-1], true
+}
+
+// refEndIndex returns the index of the } closing the reference starting at start, taking into account
+// the references nested in a default, or -1 if the reference isn't closed.
+func refEndIndex(str string, start int) int {
+	depth := 0
+	for i := start + len(refStart); i < len(str); i++ {
+		switch {
+		case strings.HasPrefix(str[i:], refStart):
+			depth++
+			i += len(refStart) - 1
+		case str[i] == refEnd:
+			if depth == 0 {
+				return i
+			}
+
+			depth--
+		}
+	}
+
+	return -1
+}
+
+// refText formats the value of a reference embedded in a string.
+func refText(val any) string {
+	if str, ok := val.(string); ok {
+		return str
+	}
+
+	if text, ok := textOf(val); ok {
+		return text
+	}
+
+	return fmt.Sprint(val)
+}
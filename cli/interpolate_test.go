@@ -0,0 +1,147 @@
+package cli_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/cybersamx/golib/reflectutils"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/cybersamx/golib/cli"
+)
+
+func TestInitFlags_Interpolation(t *testing.T) {
+	t.Setenv("IP_TEST_USER", "app")
+	t.Setenv("IP_TEST_EMPTY", "")
+
+	tests := []struct {
+		description string
+		config      string // Yaml config with the key arg.
+		args        []string
+		newTarget   func() any
+		want        any
+		wantErr     error
+	}{
+		{
+			description: "Env variable",
+			config:      "arg: postgres://${IP_TEST_USER}@localhost",
+			want:        "postgres://app@localhost",
+		},
+		{
+			description: "Env variable default",
+			config:      "arg: ${IP_TEST_UNSET:-guest}@${IP_TEST_EMPTY:-localhost}",
+			want:        "guest@localhost",
+		},
+		{
+			description: "Nested default",
+			config:      "arg: ${IP_TEST_UNSET:-${IP_TEST_USER}}",
+			want:        "app",
+		},
+		{
+			description: "Empty env variable without default",
+			config:      "arg: x${IP_TEST_EMPTY}x",
+			want:        "xx",
+		},
+		{
+			description: "Other key",
+			config:      "db:\n  host: db.local\n  port: 5432\narg: ${db.host}:${db.port}",
+			want:        "db.local:5432",
+		},
+		{
+			description: "Chain of keys",
+			config:      "a: ${b}/a\nb: ${c}/b\nc: ${IP_TEST_USER}\narg: ${a}",
+			want:        "app/b/a",
+		},
+		{
+			description: "Key set in a flag",
+			config:      "arg: ${host}:80",
+			args:        []string{"--host", "flag.local"},
+			want:        "flag.local:80",
+		},
+		{
+			description: "Key default",
+			config:      "arg: ${db.host:-localhost}",
+			want:        "localhost",
+		},
+		{
+			description: "Whole value reference keeps the type",
+			config:      "tags: [a, b]\narg: ${tags}",
+			newTarget:   func() any { return new([]string) },
+			want:        []string{"a", "b"},
+		},
+		{
+			description: "Items of a list",
+			config:      "arg: [\"${IP_TEST_USER}\", b]",
+			newTarget:   func() any { return new([]string) },
+			want:        []string{"app", "b"},
+		},
+		{
+			description: "Escape",
+			config:      "arg: $${IP_TEST_USER} costs $5",
+			want:        "${IP_TEST_USER} costs $5",
+		},
+		{
+			description: "Unresolved env variable",
+			config:      "arg: ${IP_TEST_UNSET}",
+			wantErr:     ErrUnresolvedReference,
+		},
+		{
+			description: "Unresolved key",
+			config:      "arg: ${db.host}",
+			wantErr:     ErrUnresolvedReference,
+		},
+		{
+			description: "Unterminated",
+			config:      "arg: ${db.host",
+			wantErr:     ErrUnresolvedReference,
+		},
+		{
+			description: "Self reference",
+			config:      "arg: x${arg}",
+			wantErr:     ErrReferenceCycle,
+		},
+		{
+			description: "Cycle",
+			config:      "a: ${b}\nb: ${a}\narg: ${a}",
+			wantErr:     ErrReferenceCycle,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			newTarget := test.newTarget
+			if newTarget == nil {
+				newTarget = func() any { return new(string) }
+			}
+
+			v := NewViper("ip")
+			v.SetConfigType("yaml")
+			require.NoError(t, v.ReadConfig(bytes.NewBufferString(test.config)))
+
+			var host string
+			target := newTarget()
+			bindings := []FlagBinding{
+				{Name: "host", Target: &host},
+				{Name: "arg", Target: target},
+			}
+
+			cmd := cobra.Command{Run: func(cmd *cobra.Command, args []string) {}}
+			err := InitFlags(v, cmd.Flags(), bindings)
+			if test.wantErr != nil {
+				assert.ErrorIs(t, err, test.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+
+			// Resolve the bindings again once the flags are parsed.
+			cmd.SetArgs(test.args)
+			require.NoError(t, cmd.Execute())
+			require.NoError(t, ResolveFlags(v, cmd.Flags(), bindings))
+
+			assert.Equal(t, test.want, reflectutils.Indirect(target))
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// Origin is the source a bound configuration value is resolved from.
+type Origin int
+
+const (
+	OriginDefault Origin = iota
+	OriginFile
+	OriginEnv
+	OriginFlag
+	OriginSource // A Source added by AddSource, at the precedence of its level.
+)
+
+var originStrs = [...]string{"default", "file", "env", "flag", "source"}
+
+func (o Origin) String() string {
+	if o < 0 || int(o) >= len(originStrs) {
+		return "unknown"
+	}
+
+	return originStrs[o]
+}
+
+func (o Origin) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+// valueOrigin returns the origin of the value bound to a flag name, following the precedence of flag,
+// env variable, config file and default, with the sources at the precedence of their level.
+func valueOrigin(v *viper.Viper, flags *pflag.FlagSet, name string) Origin {
+	if flag := flags.Lookup(name); flag != nil && flag.Changed {
+		return OriginFlag
+	}
+
+	origin := storedOrigin(v, name)
+	if _, level, ok, _ := lookupSource(v, name); ok && level >= origin {
+		return OriginSource
+	}
+
+	return origin
+}
+
+// storedOrigin returns the origin of the value of a key in the env variables, config files and defaults.
+func storedOrigin(v *viper.Viper, name string) Origin {
+	if isEnvSet(v, name) {
+		return OriginEnv
+	}
+
+	if v.InConfig(name) {
+		return OriginFile
+	}
+
+	return OriginDefault
+}
+
+// originRank returns the rank of the origin of the value of a key in the order of precedence. A source
+// ranks just above the values of its level.
+func originRank(v *viper.Viper, origin Origin, key string) int {
+	if origin == OriginSource {
+		_, level, _, _ := lookupSource(v, key)
+		return 2*int(level) + 1
+	}
+
+	return 2 * int(origin)
+}
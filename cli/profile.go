@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// ProfileFlag is the name of the persistent flag (and env variable and config key) of the root command
+// that sets the active profile eg. dev, staging or prod.
+const ProfileFlag = "profile"
+
+// profilesKey is the config key of the sections of the profiles eg. profiles.prod.db-url.
+const profilesKey = "profiles"
+
+var ErrUnknownProfile = errors.New("profile not found in the config")
+
+// ApplyProfile overlays the config of a profile on top of the config read into v, so a value of the
+// profile overrides the value of the same key in the base config. If profile is empty, the profile set
+// in the flag, the env variable eg. APP_PROFILE or the config key profile is applied, if any.
+//
+// The config of a profile is merged from, in this order:
+//  1. The sibling file of every loaded config file eg. config.prod.yaml for config.yaml.
+//  2. The section of the profile in the config eg. profiles.prod.
+//
+// Call it after the config files are read and before the bindings are resolved by InitFlags or
+// ResolveFlags. NewCommand applies the profile set in its persistent flag --profile. ApplyProfile
+// returns ErrUnknownProfile if the profile has no sibling file and no section.
+func ApplyProfile(v *viper.Viper, profile string) error {
+	if profile == "" {
+		profile = v.GetString(ProfileFlag)
+	}
+
+	if profile == "" {
+		return nil
+	}
+
+	found := false
+
+	files := ConfigFiles(v)
+	if len(files) == 0 && v.ConfigFileUsed() != "" {
+		// Record the config file read by viper to read it again with the sibling files on reload.
+		files = []string{v.ConfigFileUsed()}
+		setConfigFiles(v, files)
+	}
+
+	for _, file := range files {
+		sibling := profileFile(file, profile)
+		if sibling == "" {
+			continue
+		}
+
+		// The sibling file is already loaded if the profile is applied again.
+		found = true
+		if contains(files, sibling) {
+			continue
+		}
+
+		if err := mergeConfigFile(v, sibling); err != nil {
+			return err
+		}
+	}
+
+	ok, err := mergeProfileSection(v, profile)
+	if err != nil {
+		return err
+	}
+
+	if !found && !ok {
+		return fmt.Errorf("profile %s: %w", profile, ErrUnknownProfile)
+	}
+
+	state := stateOf(v)
+	state.mu.Lock()
+	state.activeProfile = profile
+	state.mu.Unlock()
+
+	return nil
+}
+
+// ActiveProfile returns the profile applied to v by ApplyProfile.
+func ActiveProfile(v *viper.Viper) string {
+	state := stateOf(v)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	return state.activeProfile
+}
+
+// profileFile returns the path of the config file of a profile next to a config file, or an empty
+// string if there is none.
+func profileFile(file, profile string) string {
+	name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+
+	return findConfigFile(filepath.Dir(file), name+"."+profile)
+}
+
+// mergeProfileSection merges the section of a profile into the config of v. It returns false if the
+// config has no section for the profile.
+func mergeProfileSection(v *viper.Viper, profile string) (bool, error) {
+	key := profilesKey + keyDelimiter + profile
+	if !v.IsSet(key) {
+		return false, nil
+	}
+
+	if err := v.MergeConfigMap(v.GetStringMap(key)); err != nil {
+		return false, fmt.Errorf("failed to merge profile %s: %w", profile, err)
+	}
+
+	return true, nil
+}
+
+func contains(items []string, item string) bool {
+	for _, it := range items {
+		if it == item {
+			return true
+		}
+	}
+
+	return false
+}
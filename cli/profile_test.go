@@ -0,0 +1,111 @@
+package cli_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/cybersamx/golib/cli"
+)
+
+func TestApplyProfile_Section(t *testing.T) {
+	t.Parallel()
+
+	v := NewViper("pf")
+	v.SetConfigType("yaml")
+	err := v.ReadConfig(bytes.NewBufferString(`
+db-url: postgres://localhost
+log-level: debug
+profiles:
+  prod:
+    db-url: postgres://prod
+`))
+	require.NoError(t, err)
+
+	err = ApplyProfile(v, "prod")
+	require.NoError(t, err)
+	assert.Equal(t, "prod", ActiveProfile(v))
+
+	var dbURL, logLevel string
+	flags := pflag.NewFlagSet("pf", pflag.ContinueOnError)
+	err = InitFlags(v, flags, []FlagBinding{
+		Bind("db-url", &dbURL, "", "database url"),
+		Bind("log-level", &logLevel, "info", "log level"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "postgres://prod", dbURL)
+	assert.Equal(t, "debug", logLevel)
+
+	report := EffectiveConfig(v, flags, nil)
+	assert.Equal(t, "prod", report.Profile)
+
+	var buf bytes.Buffer
+	require.NoError(t, report.WriteTable(&buf))
+	assert.Contains(t, buf.String(), "Profile: prod\n")
+
+	err = ApplyProfile(v, "staging")
+	assert.ErrorIs(t, err, ErrUnknownProfile)
+}
+
+func TestApplyProfile_File(t *testing.T) {
+	dir := t.TempDir()
+	cfgFile := writeFile(t, filepath.Join(dir, "config.yaml"),
+		"db-url: postgres://localhost\nlog-level: debug\nprofiles:\n  prod:\n    log-level: warn\n")
+	profileFile := writeFile(t, filepath.Join(dir, "config.prod.yaml"), "db-url: postgres://prod\nlog-level: error\n")
+
+	t.Setenv("PF_PROFILE", "prod")
+
+	v := NewViper("pf")
+	_, err := LoadConfig(v, ConfigLayers{App: "pf", SystemDir: dir, UserDir: filepath.Join(dir, "none"), WorkDir: dir})
+	require.NoError(t, err)
+
+	// The profile is set in the env variable.
+	err = ApplyProfile(v, "")
+	require.NoError(t, err)
+	assert.Equal(t, "prod", ActiveProfile(v))
+	assert.Equal(t, []string{cfgFile, profileFile}, ConfigFiles(v))
+
+	// The section of the profile is merged on top of the file of the profile.
+	assert.Equal(t, "postgres://prod", v.GetString("db-url"))
+	assert.Equal(t, "warn", v.GetString("log-level"))
+
+	// Applying the profile again is a no-op.
+	err = ApplyProfile(v, "")
+	require.NoError(t, err)
+	assert.Equal(t, []string{cfgFile, profileFile}, ConfigFiles(v))
+}
+
+func TestNewCommand_Profile(t *testing.T) {
+	dir := t.TempDir()
+	cfgFile := writeFile(t, filepath.Join(dir, "app.yaml"), "db-url: postgres://localhost\n")
+	writeFile(t, filepath.Join(dir, "app.prod.yaml"), "db-url: postgres://prod\n")
+
+	var dbURL string
+	spec := Command{
+		Use:      "app",
+		Bindings: []FlagBinding{Bind("db-url", &dbURL, "", "database url")},
+		RunE:     func(cmd *cobra.Command, args []string) error { return nil },
+	}
+
+	v := NewViper("pf")
+	cmd, err := NewCommand(v, &spec)
+	require.NoError(t, err)
+
+	cmd.SetArgs([]string{"--config", cfgFile, "--profile", "prod"})
+	require.NoError(t, cmd.Execute())
+	assert.Equal(t, "postgres://prod", dbURL)
+	assert.Equal(t, "prod", ActiveProfile(v))
+
+	cmd, err = NewCommand(NewViper("pf"), &spec)
+	require.NoError(t, err)
+
+	cmd.SetArgs([]string{"--config", cfgFile, "--profile", "qa"})
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	assert.ErrorIs(t, cmd.Execute(), ErrUnknownProfile)
+}
@@ -0,0 +1,117 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/cybersamx/golib/stringsutils"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// ConfigValue is the effective value of a FlagBinding.
+type ConfigValue struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"` // Value formatted as a flag value, with secrets masked.
+	Type   string `json:"type"`
+	Origin Origin `json:"origin"`
+	EnvVar string `json:"env"` // The env variable that overrides the value.
+}
+
+// ConfigReport lists the effective values of the bindings and where they are resolved from.
+type ConfigReport struct {
+	ConfigFile  string        `json:"configFile,omitempty"`
+	ConfigFiles []string      `json:"configFiles,omitempty"` // Files loaded by LoadConfig in the order of merging.
+	Profile     string        `json:"profile,omitempty"`     // Profile applied by ApplyProfile.
+	Values      []ConfigValue `json:"values"`
+}
+
+// EffectiveConfig returns the effective value of every binding passed to InitFlags, where the value
+// is resolved from, and the env variable that overrides it. Call it after the flags are parsed eg. to
+// implement a --print-config flag or subcommand.
+func EffectiveConfig(v *viper.Viper, flags *pflag.FlagSet, bindings []FlagBinding) *ConfigReport {
+	report := ConfigReport{
+		ConfigFile:  v.ConfigFileUsed(),
+		ConfigFiles: ConfigFiles(v),
+		Profile:     ActiveProfile(v),
+		Values:      make([]ConfigValue, 0, len(bindings)),
+	}
+
+	for _, binding := range bindings {
+		cv := ConfigValue{
+			Name:   binding.Name,
+			Origin: bindingOrigin(v, flags, &binding),
+			EnvVar: envVarName(envPrefixOf(v), binding.Name),
+		}
+
+		if flag := flags.Lookup(binding.Name); flag != nil {
+			cv.Value = flag.Value.String()
+			cv.Type = flag.Value.Type()
+		} else if rv := reflect.ValueOf(binding.Target); rv.Kind() == reflect.Pointer && !rv.IsNil() {
+			cv.Value = fmt.Sprint(rv.Elem().Interface())
+			cv.Type = rv.Type().Elem().String()
+		}
+
+		cv.Value = maskValue(&binding, cv.Value)
+		report.Values = append(report.Values, cv)
+	}
+
+	return &report
+}
+
+// WriteTable writes the report as a table.
+func (r *ConfigReport) WriteTable(w io.Writer) error {
+	switch {
+	case len(r.ConfigFiles) > 0:
+		if _, err := fmt.Fprintf(w, "Config files:\n  %s\n\n", strings.Join(r.ConfigFiles, "\n  ")); err != nil {
+			return err
+		}
+	case r.ConfigFile != "":
+		if _, err := fmt.Fprintf(w, "Config file: %s\n\n", r.ConfigFile); err != nil {
+			return err
+		}
+	}
+
+	if r.Profile != "" {
+		if _, err := fmt.Fprintf(w, "Profile: %s\n\n", r.Profile); err != nil {
+			return err
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tVALUE\tTYPE\tSOURCE\tENV")
+	for _, cv := range r.Values {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", cv.Name, cv.Value, cv.Type, cv.Origin, cv.EnvVar)
+	}
+
+	return tw.Flush()
+}
+
+// WriteJSON writes the report in json format.
+func (r *ConfigReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(r)
+}
+
+// maskValue masks the password of a value formatted as an url, and the value of a secret binding
+// that isn't an url.
+func maskValue(binding *FlagBinding, val string) string {
+	if u, err := url.Parse(val); err == nil && u.User != nil {
+		if _, set := u.User.Password(); set {
+			return stringsutils.MaskPassword(val)
+		}
+	}
+
+	if isSecret(binding) {
+		return Secret(val).String()
+	}
+
+	return val
+}
@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"reflect"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// ResolveFlags resolves the value of every binding again from the flags, env variables, config file
+// and defaults, in that order of precedence, and updates the targets. Call it when the config is read
+// or changed after InitFlags eg. when the path of the config file is set in a flag. It returns
+// BindingErrors listing every value that can't be decoded.
+func ResolveFlags(v *viper.Viper, flags *pflag.FlagSet, bindings []FlagBinding) error {
+	var errs BindingErrors
+
+	for _, binding := range bindings {
+		val, ok, err := resolveValue(v, flags, &binding)
+		if err != nil {
+			errs = append(errs, &BindingError{Name: binding.Name, Err: err})
+			continue
+		}
+
+		if ok {
+			reflect.ValueOf(binding.Target).Elem().Set(reflect.ValueOf(val))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// resolveValue returns the value of a binding resolved from the env variables, config file and
+// default, under the name or an alias of the binding. It returns false if the value is set by a flag,
+// which has already updated the target, or if the binding has a custom parser.
+func resolveValue(v *viper.Viper, flags *pflag.FlagSet, binding *FlagBinding) (any, bool, error) {
+	if binding.Parser != nil {
+		return nil, false, nil
+	}
+
+	key, origin, err := bindingKey(v, flags, binding)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if origin == OriginFlag {
+		return nil, false, nil
+	}
+
+	warnDeprecated(v, binding, key, origin)
+
+	if fileOrigin, ok := secretFileOrigin(v, flags, binding, key, origin); isSecret(binding) && ok {
+		// The file set in the flag is read when the flags are parsed.
+		if fileOrigin == OriginFlag {
+			return nil, false, nil
+		}
+
+		path, err := lookupString(v, secretFileKey(binding.Name))
+		if err != nil {
+			return nil, false, err
+		}
+
+		if path != "" {
+			secret, err := readSecret(path)
+			if err != nil {
+				return nil, false, err
+			}
+
+			val, err := decodeValue(binding.Target, secret)
+			return val, err == nil, err
+		}
+	}
+
+	if origin == OriginDefault {
+		typ := reflect.TypeOf(binding.Target).Elem()
+		if binding.Default == nil {
+			return reflect.Zero(typ).Interface(), true, nil
+		}
+
+		// The default is assignable but not necessarily identical to the type of the target.
+		def := reflect.New(typ).Elem()
+		def.Set(reflect.ValueOf(binding.Default))
+
+		return def.Interface(), true, nil
+	}
+
+	raw, err := lookupBindingValue(v, binding, key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	val, err := decodeValue(binding.Target, raw)
+	if err != nil {
+		return nil, false, decodeError(v, flags, binding, key, raw, err)
+	}
+
+	return val, true, nil
+}
@@ -0,0 +1,103 @@
+package cli_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/cybersamx/golib/cli"
+)
+
+func TestResolveFlags(t *testing.T) {
+	t.Setenv("GL_STR_ENV", "str_env")
+
+	var target targetVars
+	bindings := []FlagBinding{
+		{Name: "str-default", Target: &target.strDefault, Default: "str_default"},
+		{Name: "str-reader", Target: &target.strReader, Default: "str_reader_default"},
+		{Name: "str-env", Target: &target.strEnv, Default: "str_env_default"},
+		{Name: "str-flag", Target: &target.strFlag, Default: "str_flag_default"},
+		{Name: "number", Target: &target.number},
+	}
+
+	v := NewViper("GL")
+	cmd := cobra.Command{
+		Use: "app_test",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Read the config after the flags are parsed eg. when the path is set in a flag.
+			v.SetConfigType("yaml")
+			config := "str-reader: str_reader\nstr-env: str_env_reader\nstr-flag: str_flag_reader\nnumber: 234"
+			if err := v.ReadConfig(strings.NewReader(config)); err != nil {
+				return err
+			}
+
+			return ResolveFlags(v, cmd.Flags(), bindings)
+		},
+	}
+
+	err := InitFlags(v, cmd.Flags(), bindings)
+	require.NoError(t, err)
+
+	cmd.SetArgs([]string{"--str-flag=str_flag"})
+	err = cmd.Execute()
+	require.NoError(t, err)
+
+	assert.Equal(t, "str_default", target.strDefault)
+	assert.Equal(t, "str_reader", target.strReader)
+	assert.Equal(t, "str_env", target.strEnv)
+	assert.Equal(t, "str_flag", target.strFlag)
+	assert.Equal(t, 234, target.number)
+}
+
+func TestResolveFlags_InvalidValue(t *testing.T) {
+	var number int
+	bindings := []FlagBinding{
+		{Name: "number", Target: &number, Default: 1},
+	}
+
+	cmd := cobra.Command{Run: func(cmd *cobra.Command, args []string) {}}
+	v := NewViper("GL")
+	err := InitFlags(v, cmd.Flags(), bindings)
+	require.NoError(t, err)
+
+	v.SetConfigType("yaml")
+	err = v.ReadConfig(strings.NewReader("number: one"))
+	require.NoError(t, err)
+
+	err = ResolveFlags(v, cmd.Flags(), bindings)
+	assert.ErrorIs(t, err, ErrFlagBinding)
+	assert.Equal(t, 1, number)
+}
+
+func TestResolveFlags_SecretFile(t *testing.T) {
+	t.Setenv("RV_PW", "fromenv")
+
+	var pw, token string
+	bindings := []FlagBinding{
+		{Name: "pw", Target: &pw, Secret: true},
+		{Name: "token", Target: &token, Secret: true},
+	}
+
+	cmd := cobra.Command{Run: func(cmd *cobra.Command, args []string) {}}
+	v := NewViper("RV")
+	err := InitFlags(v, cmd.Flags(), bindings)
+	require.NoError(t, err)
+
+	// A path set in the config file doesn't override a value set in an env variable.
+	v.SetConfigType("yaml")
+	config := "pw-file: " + writeSecretFile(t, "fromfile") + "\ntoken-file: " + writeSecretFile(t, "tokenfile")
+	err = v.ReadConfig(strings.NewReader(config))
+	require.NoError(t, err)
+
+	err = ResolveFlags(v, cmd.Flags(), bindings)
+	require.NoError(t, err)
+	assert.Equal(t, "fromenv", pw)
+	assert.Equal(t, "tokenfile", token)
+
+	report := EffectiveConfig(v, cmd.Flags(), bindings)
+	assert.Equal(t, OriginEnv, report.Values[0].Origin)
+	assert.Equal(t, OriginFile, report.Values[1].Origin)
+}
@@ -0,0 +1,370 @@
+package cli
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ConfigFormat is the format of a config file.
+type ConfigFormat string
+
+const (
+	FormatYAML ConfigFormat = "yaml"
+	FormatTOML ConfigFormat = "toml"
+	FormatJSON ConfigFormat = "json"
+	FormatEnv  ConfigFormat = "env"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported config format")
+	ErrKeyConflict       = errors.New("key is both a value and a parent of other keys")
+)
+
+// configNode is a node in the tree of config keys derived from the dotted names of the bindings, eg.
+// the binding deep.nested.str is the leaf str of the node nested, which is a child of the node deep.
+type configNode struct {
+	name     string
+	binding  *FlagBinding
+	children []*configNode
+}
+
+func (n *configNode) child(name string) *configNode {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+
+	c := configNode{name: name}
+	n.children = append(n.children, &c)
+
+	return &c
+}
+
+func (n *configNode) isLeaf() bool {
+	return n.binding != nil
+}
+
+// configTree returns the tree of the config keys of the bindings in the order of the bindings.
+func configTree(bindings []FlagBinding) (*configNode, error) {
+	root := configNode{}
+
+	for i := range bindings {
+		node := &root
+		for _, name := range strings.Split(bindings[i].Name, keyDelimiter) {
+			if node.isLeaf() {
+				return nil, fmt.Errorf("key %s: %w", bindings[i].Name, ErrKeyConflict)
+			}
+
+			node = node.child(name)
+		}
+
+		if node.isLeaf() || len(node.children) > 0 {
+			return nil, fmt.Errorf("key %s: %w", bindings[i].Name, ErrKeyConflict)
+		}
+
+		node.binding = &bindings[i]
+	}
+
+	return &root, nil
+}
+
+// WriteSampleConfig writes an example config file in the given format, with the defaults of the
+// bindings as the values and the usages as comments (except for json, which doesn't support comments).
+// Nested keys are derived from the dotted names of the bindings. The names of the env variables in
+// the env format are prefixed with envPrefix, see NewViper. The defaults of the secrets are left out.
+func WriteSampleConfig(w io.Writer, format ConfigFormat, envPrefix string, bindings []FlagBinding) error {
+	if format == FormatEnv {
+		return writeSampleEnv(w, envPrefix, bindings)
+	}
+
+	root, err := configTree(bindings)
+	if err != nil {
+		return err
+	}
+
+	sw := sampleWriter{w: w}
+
+	switch format {
+	case FormatYAML:
+		sw.writeYAML(root, 0)
+	case FormatTOML:
+		sw.writeTOML(root, "")
+	case FormatJSON:
+		sw.writeJSON(root, 0)
+		sw.printf("\n")
+	default:
+		return fmt.Errorf("format %s: %w", format, ErrUnsupportedFormat)
+	}
+
+	return sw.err
+}
+
+// sampleWriter keeps the first write error so that the writing functions don't need to check for
+// errors on every line.
+type sampleWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (sw *sampleWriter) printf(format string, args ...any) {
+	if sw.err != nil {
+		return
+	}
+
+	_, sw.err = fmt.Fprintf(sw.w, format, args...)
+}
+
+func (sw *sampleWriter) writeComment(indent string, binding *FlagBinding) {
+	if binding.Usage != "" {
+		sw.printf("%s# %s\n", indent, binding.Usage)
+	}
+
+	if isSecret(binding) {
+		sw.printf("%s# Secret, may be read from the file at the path set in %s.\n",
+			indent, secretFileKey(binding.Name))
+	}
+}
+
+func (sw *sampleWriter) writeYAML(node *configNode, depth int) {
+	indent := strings.Repeat("  ", depth)
+
+	for _, c := range node.children {
+		if !c.isLeaf() {
+			sw.printf("%s%s:\n", indent, c.name)
+			sw.writeYAML(c, depth+1)
+			continue
+		}
+
+		sw.writeComment(indent, c.binding)
+
+		// A json value is also a valid yaml value in the flow style.
+		sw.printf("%s%s: %s\n", indent, c.name, jsonValue(sampleValue(c.binding)))
+	}
+}
+
+func (sw *sampleWriter) writeTOML(node *configNode, table string) {
+	// Keys of a table must come before the sub-tables.
+	for _, c := range node.children {
+		if !c.isLeaf() {
+			continue
+		}
+
+		sw.writeComment("", c.binding)
+		sw.printf("%s = %s\n", c.name, tomlValue(sampleValue(c.binding)))
+	}
+
+	for _, c := range node.children {
+		if c.isLeaf() {
+			continue
+		}
+
+		name := c.name
+		if table != "" {
+			name = table + keyDelimiter + c.name
+		}
+
+		sw.printf("\n[%s]\n", name)
+		sw.writeTOML(c, name)
+	}
+}
+
+func (sw *sampleWriter) writeJSON(node *configNode, depth int) {
+	indent := strings.Repeat("  ", depth+1)
+
+	sw.printf("{\n")
+	for i, c := range node.children {
+		sw.printf("%s%s: ", indent, jsonValue(c.name))
+		if c.isLeaf() {
+			sw.printf("%s", jsonValue(sampleValue(c.binding)))
+		} else {
+			sw.writeJSON(c, depth+1)
+		}
+
+		if i < len(node.children)-1 {
+			sw.printf(",")
+		}
+
+		sw.printf("\n")
+	}
+	sw.printf("%s}", strings.Repeat("  ", depth))
+}
+
+func writeSampleEnv(w io.Writer, envPrefix string, bindings []FlagBinding) error {
+	sw := sampleWriter{w: w}
+
+	for i := range bindings {
+		binding := &bindings[i]
+		sw.writeComment("", binding)
+		sw.printf("%s=%s\n", envVarName(envPrefix, binding.Name), envValue(sampleValue(binding)))
+	}
+
+	return sw.err
+}
+
+// sampleValue returns the default of a binding converted to a value that can be encoded in json, or the
+// zero value of the target if the binding has no default.
+func sampleValue(binding *FlagBinding) any {
+	if isSecret(binding) {
+		return ""
+	}
+
+	val := binding.Default
+	if val == nil {
+		rv := reflect.ValueOf(binding.Target)
+		if rv.Kind() != reflect.Pointer || rv.IsNil() {
+			return ""
+		}
+
+		val = rv.Elem().Interface()
+	}
+
+	return plainValue(val)
+}
+
+// plainValue converts a value to a string, number, bool, slice or map as it's set in a config file.
+func plainValue(val any) any {
+	switch typed := val.(type) {
+	case nil:
+		return ""
+	case HexBytes:
+		return hex.EncodeToString(typed)
+	case []byte:
+		return base64.StdEncoding.EncodeToString(typed)
+	}
+
+	if text, ok := textOf(val); ok {
+		return text
+	}
+
+	rv := reflect.ValueOf(val)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		slice := make([]any, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			slice = append(slice, plainValue(rv.Index(i).Interface()))
+		}
+
+		return slice
+	case reflect.Map:
+		m := make(map[string]any, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			m[fmt.Sprint(iter.Key().Interface())] = plainValue(iter.Value().Interface())
+		}
+
+		return m
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return ""
+		}
+
+		return plainValue(rv.Elem().Interface())
+	}
+
+	return val
+}
+
+// textOf returns the text of a value if its type, or the pointer to its type eg. net.IPNet, implements
+// encoding.TextMarshaler or fmt.Stringer.
+func textOf(val any) (string, bool) {
+	ptr := reflect.New(reflect.TypeOf(val))
+	ptr.Elem().Set(reflect.ValueOf(val))
+
+	switch typed := ptr.Interface().(type) {
+	case encoding.TextMarshaler:
+		text, err := typed.MarshalText()
+		return string(text), err == nil
+	case fmt.Stringer:
+		return typed.String(), true
+	}
+
+	return "", false
+}
+
+func jsonValue(val any) string {
+	buf, err := json.Marshal(val)
+	if err != nil {
+		return strconv.Quote(fmt.Sprint(val))
+	}
+
+	return string(buf)
+}
+
+func tomlValue(val any) string {
+	switch typed := val.(type) {
+	case []any:
+		items := make([]string, 0, len(typed))
+		for _, item := range typed {
+			items = append(items, tomlValue(item))
+		}
+
+		return "[" + strings.Join(items, ", ") + "]"
+	case map[string]any:
+		pairs := make([]string, 0, len(typed))
+		for _, key := range sortedKeys(typed) {
+			pairs = append(pairs, fmt.Sprintf("%s = %s", strconv.Quote(key), tomlValue(typed[key])))
+		}
+
+		return "{ " + strings.Join(pairs, ", ") + " }"
+	}
+
+	return jsonValue(val)
+}
+
+// pairEscaper escapes a key or a value of a map encoded in k1=v1,k2=v2 format, see parsePairs.
+var pairEscaper = strings.NewReplacer(`\`, `\\`, ",", `\,`, "=", `\=`)
+
+func envValue(val any) string {
+	var str string
+
+	switch typed := val.(type) {
+	case []any:
+		fields := make([]string, 0, len(typed))
+		for _, item := range typed {
+			fields = append(fields, fmt.Sprint(item))
+		}
+
+		var sb strings.Builder
+		cw := csv.NewWriter(&sb)
+		_ = cw.Write(fields)
+		cw.Flush()
+		str = strings.TrimSuffix(sb.String(), "\n")
+	case map[string]any:
+		pairs := make([]string, 0, len(typed))
+		for _, key := range sortedKeys(typed) {
+			pairs = append(pairs, pairEscaper.Replace(key)+"="+pairEscaper.Replace(fmt.Sprint(typed[key])))
+		}
+
+		str = strings.Join(pairs, ",")
+	default:
+		str = fmt.Sprint(val)
+	}
+
+	if strings.ContainsAny(str, " \t\"'#$\\") {
+		return strconv.Quote(str)
+	}
+
+	return str
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,229 @@
+package cli_test
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kylelemons/godebug/pretty"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/cybersamx/golib/cli"
+)
+
+type sampleVars struct {
+	str      string
+	quoted   string
+	nested   string
+	number   int
+	boolean  bool
+	duration time.Duration
+	strList  []string
+	strMap   map[string]string
+	level    Level
+	password string
+}
+
+func sampleBindings(target *sampleVars) []FlagBinding {
+	return []FlagBinding{
+		{Usage: "string", Name: "str", Target: &target.str, Default: "str_default"},
+		{Usage: "quoted string", Name: "quoted", Target: &target.quoted, Default: `say "hi" # there`},
+		{Usage: "nested string", Name: "deep.nested.str", Target: &target.nested, Default: "nested_default"},
+		{Usage: "int", Name: "deep.number", Target: &target.number, Default: 123},
+		{Usage: "bool", Name: "boolean", Target: &target.boolean, Default: true},
+		{Usage: "duration", Name: "duration", Target: &target.duration, Default: time.Hour + time.Second},
+		{Usage: "string slice", Name: "str-list", Target: &target.strList, Default: []string{"a", "b,c"}},
+		{Usage: "string map", Name: "str-map", Target: &target.strMap, Default: map[string]string{"k1": "v1"}},
+		{Usage: "log level", Name: "log.level", Target: &target.level, Default: Info},
+		{Usage: "password", Name: "password", Target: &target.password, Default: "secret", Secret: true},
+	}
+}
+
+func TestWriteSampleConfig_YAML(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	err := WriteSampleConfig(&buf, FormatYAML, "GL", sampleBindings(new(sampleVars)))
+	require.NoError(t, err)
+
+	want := `# string
+str: "str_default"
+# quoted string
+quoted: "say \"hi\" # there"
+deep:
+  nested:
+    # nested string
+    str: "nested_default"
+  # int
+  number: 123
+# bool
+boolean: true
+# duration
+duration: "1h0m1s"
+# string slice
+str-list: ["a","b,c"]
+# string map
+str-map: {"k1":"v1"}
+log:
+  # log level
+  level: "info"
+# password
+# Secret, may be read from the file at the path set in password-file.
+password: ""
+`
+	assert.Equal(t, want, buf.String())
+}
+
+func TestWriteSampleConfig_TOML(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	err := WriteSampleConfig(&buf, FormatTOML, "GL", sampleBindings(new(sampleVars)))
+	require.NoError(t, err)
+
+	want := `# string
+str = "str_default"
+# quoted string
+quoted = "say \"hi\" # there"
+# bool
+boolean = true
+# duration
+duration = "1h0m1s"
+# string slice
+str-list = ["a", "b,c"]
+# string map
+str-map = { "k1" = "v1" }
+# password
+# Secret, may be read from the file at the path set in password-file.
+password = ""
+
+[deep]
+# int
+number = 123
+
+[deep.nested]
+# nested string
+str = "nested_default"
+
+[log]
+# log level
+level = "info"
+`
+	assert.Equal(t, want, buf.String())
+}
+
+func TestWriteSampleConfig_Env(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	err := WriteSampleConfig(&buf, FormatEnv, "GL", sampleBindings(new(sampleVars)))
+	require.NoError(t, err)
+
+	want := `# string
+GL_STR=str_default
+# quoted string
+GL_QUOTED="say \"hi\" # there"
+# nested string
+GL_DEEP_NESTED_STR=nested_default
+# int
+GL_DEEP_NUMBER=123
+# bool
+GL_BOOLEAN=true
+# duration
+GL_DURATION=1h0m1s
+# string slice
+GL_STR_LIST="a,\"b,c\""
+# string map
+GL_STR_MAP=k1=v1
+# log level
+GL_LOG_LEVEL=info
+# password
+# Secret, may be read from the file at the path set in password-file.
+GL_PASSWORD=
+`
+	assert.Equal(t, want, buf.String())
+}
+
+// TestWriteSampleConfig_RoundTrip checks that the sample config of every format resolves to the
+// defaults of the bindings.
+func TestWriteSampleConfig_RoundTrip(t *testing.T) {
+	formats := []ConfigFormat{FormatYAML, FormatTOML, FormatJSON, FormatEnv}
+
+	want := sampleVars{
+		str:      "str_default",
+		quoted:   `say "hi" # there`,
+		nested:   "nested_default",
+		number:   123,
+		boolean:  true,
+		duration: time.Hour + time.Second,
+		strList:  []string{"a", "b,c"},
+		strMap:   map[string]string{"k1": "v1"},
+		level:    Info,
+	}
+
+	for _, format := range formats {
+		t.Run(string(format), func(t *testing.T) {
+			var buf bytes.Buffer
+			err := WriteSampleConfig(&buf, format, "GL", sampleBindings(new(sampleVars)))
+			require.NoError(t, err)
+
+			v := NewViper("GL")
+			if format == FormatEnv {
+				scanner := bufio.NewScanner(&buf)
+				for scanner.Scan() {
+					line := scanner.Text()
+					if strings.HasPrefix(line, "#") {
+						continue
+					}
+
+					key, val, _ := strings.Cut(line, "=")
+					if unquoted, err := strconv.Unquote(val); err == nil {
+						val = unquoted
+					}
+
+					t.Setenv(key, val)
+				}
+			} else {
+				v.SetConfigType(string(format))
+				err = v.ReadConfig(&buf)
+				require.NoError(t, err)
+			}
+
+			// Bind with different defaults so the values must come from the sample config.
+			var target sampleVars
+			bindings := sampleBindings(&target)
+			for i := range bindings {
+				bindings[i].Default = nil
+			}
+
+			cmd := cobra.Command{Run: func(cmd *cobra.Command, args []string) {}}
+			err = InitFlags(v, cmd.Flags(), bindings)
+			require.NoError(t, err)
+
+			diff := pretty.Compare(want, target)
+			assert.Emptyf(t, diff, "want: %+v, got: %+v", want, target)
+		})
+	}
+}
+
+func TestWriteSampleConfig_Invalid(t *testing.T) {
+	t.Parallel()
+
+	var str, nested string
+	bindings := []FlagBinding{
+		{Name: "deep", Target: &str},
+		{Name: "deep.nested", Target: &nested},
+	}
+
+	var buf bytes.Buffer
+	err := WriteSampleConfig(&buf, FormatYAML, "GL", bindings)
+	assert.ErrorIs(t, err, ErrKeyConflict)
+
+	err = WriteSampleConfig(&buf, "xml", "GL", nil)
+	assert.ErrorIs(t, err, ErrUnsupportedFormat)
+}
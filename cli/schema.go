@@ -0,0 +1,250 @@
+package cli
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+const jsonSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema is a JSON Schema document, or a subschema, describing a config file.
+type JSONSchema struct {
+	Schema      string `json:"$schema,omitempty"`
+	ID          string `json:"$id,omitempty"`
+	Ref         string `json:"$ref,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+
+	Type                 string                 `json:"type,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	AdditionalProperties any                    `json:"additionalProperties,omitempty"` // False or a *JSONSchema.
+	Items                *JSONSchema            `json:"items,omitempty"`
+
+	Default         any      `json:"default,omitempty"`
+	Enum            []any    `json:"enum,omitempty"`
+	Minimum         *float64 `json:"minimum,omitempty"`
+	Maximum         *float64 `json:"maximum,omitempty"`
+	MinLength       *int     `json:"minLength,omitempty"`
+	MaxLength       *int     `json:"maxLength,omitempty"`
+	MinItems        *int     `json:"minItems,omitempty"`
+	MaxItems        *int     `json:"maxItems,omitempty"`
+	MinProperties   *int     `json:"minProperties,omitempty"`
+	MaxProperties   *int     `json:"maxProperties,omitempty"`
+	Pattern         string   `json:"pattern,omitempty"`
+	ContentEncoding string   `json:"contentEncoding,omitempty"`
+	Deprecated      bool     `json:"deprecated,omitempty"`
+}
+
+// NewJSONSchema returns the JSON Schema of a config file setting the bindings, to validate a config
+// file in an editor or in CI. Use StructBindings to get the bindings of a tagged struct.
+//
+// Every key is described with its type, default, usage and the rules Enum, Min, Max and Pattern.
+// Unknown keys are rejected, except the file of a secret eg. db-password-file, the aliases, which are
+// marked deprecated, and the keys of the profiles, see ApplyProfile. Required isn't in the schema as a
+// required value can also be set in a flag or an env variable, nor Validate, and the default of a
+// secret is left out. It returns ErrKeyConflict if a key is both a value and a parent of other keys.
+func NewJSONSchema(bindings []FlagBinding) (*JSONSchema, error) {
+	root, err := configTree(schemaBindings(bindings))
+	if err != nil {
+		return nil, err
+	}
+
+	schema := objectSchema(root)
+	schema.Schema = jsonSchemaDraft
+	schema.Properties[ProfileFlag] = &JSONSchema{Type: "string", Description: "profile overlaid on the config"}
+	schema.Properties[profilesKey] = &JSONSchema{
+		Type:                 "object",
+		Description:          "config of every profile, overlaid on the config when the profile is active",
+		AdditionalProperties: &JSONSchema{Ref: "#"},
+	}
+
+	return schema, nil
+}
+
+// WriteJSON writes the schema in json format.
+func (s *JSONSchema) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(s)
+}
+
+// schemaBindings returns the bindings with a binding for the file of every secret and for every alias.
+func schemaBindings(bindings []FlagBinding) []FlagBinding {
+	all := make([]FlagBinding, 0, len(bindings))
+
+	for _, binding := range bindings {
+		all = append(all, binding)
+
+		if isSecret(&binding) {
+			all = append(all, FlagBinding{
+				Name:   secretFileKey(binding.Name),
+				Usage:  fmt.Sprintf("path to a file containing the value of %s", binding.Name),
+				Target: new(string),
+			})
+		}
+
+		for _, alias := range binding.Aliases {
+			aliased := binding
+			aliased.Name = alias
+			aliased.Aliases = nil
+			aliased.Deprecated = fmt.Sprintf("use %s instead", binding.Name)
+			all = append(all, aliased)
+		}
+	}
+
+	return all
+}
+
+func objectSchema(node *configNode) *JSONSchema {
+	schema := JSONSchema{
+		Type:                 "object",
+		Properties:           make(map[string]*JSONSchema, len(node.children)),
+		AdditionalProperties: false,
+	}
+
+	for _, c := range node.children {
+		if c.isLeaf() {
+			schema.Properties[c.name] = bindingSchema(c.binding)
+		} else {
+			schema.Properties[c.name] = objectSchema(c)
+		}
+	}
+
+	return &schema
+}
+
+func bindingSchema(binding *FlagBinding) *JSONSchema {
+	schema := &JSONSchema{}
+
+	if rv := reflect.ValueOf(binding.Target); binding.Parser == nil && rv.Kind() == reflect.Pointer {
+		schema = typeSchema(rv.Type().Elem())
+
+		if binding.Default != nil && !isSecret(binding) {
+			schema.Default = plainValue(binding.Default)
+		}
+	}
+
+	schema.Description = binding.Usage
+	if binding.Deprecated != "" {
+		schema.Deprecated = true
+		schema.Description = fmt.Sprintf("%s (deprecated: %s)", binding.Usage, binding.Deprecated)
+	}
+
+	addRules(schema, binding)
+
+	return schema
+}
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	ipType       = reflect.TypeOf(net.IP{})
+	hexBytesType = reflect.TypeOf(HexBytes{})
+	bytesType    = reflect.TypeOf([]byte{})
+
+	pflagValueType      = reflect.TypeOf((*pflag.Value)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// typeSchema returns the schema of a value of a target type in a config file.
+func typeSchema(typ reflect.Type) *JSONSchema {
+	// Types decoded from a string, which may also be slices.
+	switch {
+	case typ == durationType:
+		return &JSONSchema{Type: "string", Pattern: `^([-+]?([0-9]*(\.[0-9]*)?[a-zµ]+)+|0)$`}
+	case typ == ipType:
+		return &JSONSchema{Type: "string"}
+	case typ == hexBytesType:
+		return &JSONSchema{Type: "string", Pattern: `^([0-9a-fA-F]{2})*$`}
+	case typ == bytesType:
+		return &JSONSchema{Type: "string", ContentEncoding: "base64"}
+	case reflect.PointerTo(typ).Implements(pflagValueType), reflect.PointerTo(typ).Implements(textUnmarshalerType):
+		return &JSONSchema{Type: "string"}
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		return &JSONSchema{Type: "string"}
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &JSONSchema{Type: "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &JSONSchema{Type: "integer", Minimum: floatPtr(0)}
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &JSONSchema{Type: "array", Items: typeSchema(typ.Elem())}
+	case reflect.Map:
+		return &JSONSchema{Type: "object", AdditionalProperties: typeSchema(typ.Elem())}
+	case reflect.Struct:
+		// eg. net.IPNet.
+		return &JSONSchema{Type: "string"}
+	}
+
+	return &JSONSchema{}
+}
+
+// addRules adds the validation rules of a binding to its schema. Enum and Pattern apply to the items of
+// an array, and Min and Max to a number or the length of a string, an array or an object, like
+// ValidateFlags.
+func addRules(schema *JSONSchema, binding *FlagBinding) {
+	target := schema
+	if schema.Type == "array" && schema.Items != nil {
+		target = schema.Items
+	}
+
+	for _, item := range binding.Enum {
+		target.Enum = append(target.Enum, plainValue(item))
+	}
+
+	if binding.Pattern != "" {
+		target.Pattern = binding.Pattern
+	}
+
+	minVal, hasMin := toFloat64(binding.Min)
+	maxVal, hasMax := toFloat64(binding.Max)
+	if !hasMin && !hasMax {
+		return
+	}
+
+	bound := func(has bool, f float64) *int {
+		if !has {
+			return nil
+		}
+
+		n := int(f)
+		return &n
+	}
+
+	switch schema.Type {
+	case "integer", "number":
+		if hasMin {
+			schema.Minimum = floatPtr(minVal)
+		}
+
+		if hasMax {
+			schema.Maximum = floatPtr(maxVal)
+		}
+	case "array":
+		schema.MinItems, schema.MaxItems = bound(hasMin, minVal), bound(hasMax, maxVal)
+	case "object":
+		schema.MinProperties, schema.MaxProperties = bound(hasMin, minVal), bound(hasMax, maxVal)
+	case "string":
+		// A string of another type eg. a duration is compared as a number, which has no equivalent.
+		if reflect.TypeOf(binding.Target).Elem().Kind() == reflect.String {
+			schema.MinLength, schema.MaxLength = bound(hasMin, minVal), bound(hasMax, maxVal)
+		}
+	}
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
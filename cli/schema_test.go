@@ -0,0 +1,186 @@
+package cli_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/cybersamx/golib/cli"
+)
+
+func TestNewJSONSchema(t *testing.T) {
+	t.Parallel()
+
+	var (
+		host     string
+		port     uint16
+		timeout  time.Duration
+		tags     []string
+		labels   map[string]int
+		level    Level
+		password string
+		name     string
+	)
+
+	bindings := []FlagBinding{
+		Bind("store.db.host", &host, "localhost", "database host", WithPattern(`^[a-z.]+$`), WithRange(1, 253)),
+		Bind("store.db.port", &port, 5432, "database port", WithRange(1, 65535)),
+		Bind("timeout", &timeout, time.Second, "timeout"),
+		Bind("tags", &tags, []string{"a"}, "tags", WithEnum("a", "b"), WithRange(1, 3)),
+		Bind("labels", &labels, nil, "labels"),
+		Bind("level", &level, Info, "log level", WithEnum(Warn, Info), WithAliases("log-level")),
+		Bind("password", &password, "secret", "password", WithSecret()),
+		Bind("name", &name, "", "name", WithDeprecated("use store.db.host")),
+	}
+
+	schema, err := NewJSONSchema(bindings)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, schema.WriteJSON(&buf))
+
+	want := `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "type": "object",
+  "properties": {
+    "labels": {
+      "description": "labels",
+      "type": "object",
+      "additionalProperties": {
+        "type": "integer"
+      },
+      "default": {}
+    },
+    "level": {
+      "description": "log level",
+      "type": "string",
+      "default": "info",
+      "enum": [
+        "warn",
+        "info"
+      ]
+    },
+    "log-level": {
+      "description": "log level (deprecated: use level instead)",
+      "type": "string",
+      "default": "info",
+      "enum": [
+        "warn",
+        "info"
+      ],
+      "deprecated": true
+    },
+    "name": {
+      "description": "name (deprecated: use store.db.host)",
+      "type": "string",
+      "default": "",
+      "deprecated": true
+    },
+    "password": {
+      "description": "password",
+      "type": "string"
+    },
+    "password-file": {
+      "description": "path to a file containing the value of password",
+      "type": "string"
+    },
+    "profile": {
+      "description": "profile overlaid on the config",
+      "type": "string"
+    },
+    "profiles": {
+      "description": "config of every profile, overlaid on the config when the profile is active",
+      "type": "object",
+      "additionalProperties": {
+        "$ref": "#"
+      }
+    },
+    "store": {
+      "type": "object",
+      "properties": {
+        "db": {
+          "type": "object",
+          "properties": {
+            "host": {
+              "description": "database host",
+              "type": "string",
+              "default": "localhost",
+              "minLength": 1,
+              "maxLength": 253,
+              "pattern": "^[a-z.]+$"
+            },
+            "port": {
+              "description": "database port",
+              "type": "integer",
+              "default": 5432,
+              "minimum": 1,
+              "maximum": 65535
+            }
+          },
+          "additionalProperties": false
+        }
+      },
+      "additionalProperties": false
+    },
+    "tags": {
+      "description": "tags",
+      "type": "array",
+      "items": {
+        "type": "string",
+        "enum": [
+          "a",
+          "b"
+        ]
+      },
+      "default": [
+        "a"
+      ],
+      "minItems": 1,
+      "maxItems": 3
+    },
+    "timeout": {
+      "description": "timeout",
+      "type": "string",
+      "default": "1s",
+      "pattern": "^([-+]?([0-9]*(\\.[0-9]*)?[a-zµ]+)+|0)$"
+    }
+  },
+  "additionalProperties": false
+}
+`
+	assert.Equal(t, want, buf.String())
+
+	// The output is valid json.
+	var m map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &m))
+}
+
+func TestNewJSONSchema_Struct(t *testing.T) {
+	t.Parallel()
+
+	type config struct {
+		Store struct {
+			URL string `flag:"url" usage:"database url" default:"postgres://localhost"`
+		} `flag:"store"`
+		Verbose bool `flag:"verbose" usage:"verbose output"`
+	}
+
+	bindings, err := StructBindings(new(config))
+	require.NoError(t, err)
+
+	schema, err := NewJSONSchema(bindings)
+	require.NoError(t, err)
+
+	store := schema.Properties["store"]
+	require.NotNil(t, store)
+	assert.Equal(t, "object", store.Type)
+	assert.Equal(t, "postgres://localhost", store.Properties["url"].Default)
+	assert.Equal(t, "boolean", schema.Properties["verbose"].Type)
+
+	_, err = NewJSONSchema([]FlagBinding{Bind("a", &store.Type, "", ""), Bind("a.b", &store.Type, "", "")})
+	assert.ErrorIs(t, err, ErrKeyConflict)
+}
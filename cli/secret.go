@@ -0,0 +1,170 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+const (
+	maskedValue = "******"
+
+	// Suffix of the flag (and env variable and config key) that sets the path of a file containing the
+	// value of a secret, eg. --db-password-file for --db-password.
+	secretFileSuffix = "-file"
+)
+
+// Secret is a string that is masked when printed with fmt, marshaled or displayed in the usage of a
+// flag. Use Value to get the actual string.
+type Secret string
+
+// Value returns the unmasked string.
+func (s Secret) Value() string {
+	return string(s)
+}
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+
+	return maskedValue
+}
+
+func (s Secret) GoString() string {
+	return fmt.Sprintf("cli.Secret(%q)", s.String())
+}
+
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Secret) UnmarshalText(text []byte) error {
+	*s = Secret(text)
+	return nil
+}
+
+// Set implements pflag.Value.
+func (s *Secret) Set(str string) error {
+	*s = Secret(str)
+	return nil
+}
+
+// Type implements pflag.Value.
+func (s *Secret) Type() string {
+	return "secret"
+}
+
+// isSecret returns true if the value of a binding must be masked.
+func isSecret(binding *FlagBinding) bool {
+	_, ok := binding.Target.(*Secret)
+	return binding.Secret || ok
+}
+
+func secretFileKey(name string) string {
+	return name + secretFileSuffix
+}
+
+// secretFileValue is a pflag.Value that reads the value of a secret from the file at the path set in
+// the flag.
+type secretFileValue struct {
+	path   string
+	target any
+}
+
+func (s *secretFileValue) Set(path string) error {
+	if err := readSecretFile(path, s.target); err != nil {
+		return err
+	}
+
+	s.path = path
+
+	return nil
+}
+
+func (s *secretFileValue) String() string {
+	return s.path
+}
+
+func (s *secretFileValue) Type() string {
+	return "file"
+}
+
+func readSecretFile(path string, target any) error {
+	secret, err := readSecret(path)
+	if err != nil {
+		return err
+	}
+
+	return setTarget(target, secret)
+}
+
+func readSecret(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	// Files mounted by Docker and Kubernetes secrets often end with a newline.
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// bindSecret hides the default value of a secret flag from the usage and sets up the flag, env
+// variable and config key that set the path of a file containing the secret eg. --db-password-file,
+// APP_DB_PASSWORD_FILE and db-password-file for the binding db-password. The file is read if its path
+// takes precedence over the value of the secret from origin under key, eg. a path set in an env
+// variable over a value set in a config file, but not a path set in a config file over a value set in
+// an env variable.
+func bindSecret(v *viper.Viper, flags *pflag.FlagSet, binding *FlagBinding, key string, origin Origin) error {
+	flags.Lookup(binding.Name).DefValue = ""
+
+	fileKey := secretFileKey(binding.Name)
+
+	var path string
+	if _, ok := secretFileOrigin(v, flags, binding, key, origin); ok {
+		var err error
+		if path, err = lookupString(v, fileKey); err != nil {
+			return err
+		}
+	}
+
+	usage := fmt.Sprintf("path to a file containing the value of --%s", binding.Name)
+	flags.Var(&secretFileValue{target: binding.Target}, fileKey, usage)
+	if err := v.BindPFlag(fileKey, flags.Lookup(fileKey)); err != nil {
+		return err
+	}
+
+	if path != "" {
+		return readSecretFile(path, binding.Target)
+	}
+
+	return nil
+}
+
+// bindingOrigin returns the origin of the value of a binding, taking into account the aliases and the
+// file of a secret.
+func bindingOrigin(v *viper.Viper, flags *pflag.FlagSet, binding *FlagBinding) Origin {
+	// A conflict between the name and an alias is reported when the value is resolved.
+	key, origin, _ := bindingKey(v, flags, binding)
+	if !isSecret(binding) {
+		return origin
+	}
+
+	if fileOrigin, ok := secretFileOrigin(v, flags, binding, key, origin); ok {
+		return fileOrigin
+	}
+
+	return origin
+}
+
+// secretFileOrigin returns the origin of the path of the file of a secret, and true if the path takes
+// precedence over the value of the secret from origin under key.
+func secretFileOrigin(v *viper.Viper, flags *pflag.FlagSet, binding *FlagBinding, key string, origin Origin) (Origin, bool) {
+	fileKey := secretFileKey(binding.Name)
+	fileOrigin := valueOrigin(v, flags, fileKey)
+
+	return fileOrigin, originRank(v, fileOrigin, fileKey) > originRank(v, origin, key)
+}
@@ -0,0 +1,257 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+var ErrInvalidSourceLevel = errors.New("source level must be default, file or env")
+
+// Source is a source of config values other than the flags, env variables and config files eg. a
+// directory of files, a .env file or a key-value store like etcd or Consul.
+type Source interface {
+	// Get returns the value of a key eg. db.host, or false if the source has no value for the key. A
+	// string value is decoded like the value of an env variable.
+	Get(key string) (any, bool, error)
+
+	// Watch calls onChange in the background every time the values of the source change, until ctx is
+	// done. It returns an error if the source can't be watched.
+	Watch(ctx context.Context, onChange func()) error
+}
+
+// sourceEntry is a source added to a Viper instance with the level of its values.
+type sourceEntry struct {
+	source Source
+	level  Origin
+}
+
+// AddSource adds a source of the values of the bindings set up with v. The values of the source take
+// precedence over the values of level eg. OriginFile, and the values of the levels below, but not the
+// values of the levels above. For example, a source added at OriginFile overrides the config files and
+// the defaults, and is overridden by the env variables and the flags. A source added last takes
+// precedence over the sources added before at the same level.
+//
+// Add the sources before InitFlags. The value of a key set by a source has the origin OriginSource. A
+// ConfigWatcher resolves the bindings again when a source changes. AddSource returns
+// ErrInvalidSourceLevel if level isn't OriginDefault, OriginFile or OriginEnv.
+func AddSource(v *viper.Viper, source Source, level Origin) error {
+	if level < OriginDefault || level > OriginEnv {
+		return fmt.Errorf("%s: %w", level, ErrInvalidSourceLevel)
+	}
+
+	state := stateOf(v)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	entries := append(append([]sourceEntry(nil), state.sources...), sourceEntry{source: source, level: level})
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].level < entries[j].level
+	})
+
+	state.sources = entries
+
+	return nil
+}
+
+// sourcesOf returns a copy of the sources added to v, sorted by level.
+func sourcesOf(v *viper.Viper) []sourceEntry {
+	state := stateOf(v)
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	return append([]sourceEntry(nil), state.sources...)
+}
+
+// lookupSource returns the value of a key from the source of v with the highest precedence that has a
+// value for the key, and the level of the source. A source failing to get the value has it, so that the
+// error is reported when the value is resolved.
+func lookupSource(v *viper.Viper, key string) (any, Origin, bool, error) {
+	entries := sourcesOf(v)
+	for i := len(entries) - 1; i >= 0; i-- {
+		val, ok, err := entries[i].source.Get(key)
+		if err != nil {
+			return nil, entries[i].level, true, fmt.Errorf("failed to get %s from source: %w", key, err)
+		}
+
+		if ok {
+			return val, entries[i].level, true, nil
+		}
+	}
+
+	return nil, OriginDefault, false, nil
+}
+
+// sourceValue returns the value of a key from the sources of v if it takes precedence over the value of
+// the key in the env variables, config files and defaults.
+func sourceValue(v *viper.Viper, key string) (any, bool, error) {
+	val, level, ok, err := lookupSource(v, key)
+	if !ok || level < storedOrigin(v, key) {
+		return nil, false, nil
+	}
+
+	return val, true, err
+}
+
+// MapSource is a Source of the values in a map, for tests. The keys aren't case-sensitive.
+type MapSource struct {
+	mu       sync.RWMutex
+	values   map[string]any
+	watchers map[*func()]context.Context
+}
+
+// NewMapSource returns a MapSource of a copy of values.
+func NewMapSource(values map[string]any) *MapSource {
+	s := MapSource{
+		values:   make(map[string]any, len(values)),
+		watchers: make(map[*func()]context.Context),
+	}
+
+	for key, val := range values {
+		s.values[strings.ToLower(key)] = val
+	}
+
+	return &s
+}
+
+// Get returns the value of a key.
+func (s *MapSource) Get(key string) (any, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	val, ok := s.values[strings.ToLower(key)]
+
+	return val, ok, nil
+}
+
+// Set sets the value of a key and notifies the watchers.
+func (s *MapSource) Set(key string, val any) {
+	s.mu.Lock()
+	s.values[strings.ToLower(key)] = val
+	s.mu.Unlock()
+
+	s.notify()
+}
+
+// Delete deletes a key and notifies the watchers.
+func (s *MapSource) Delete(key string) {
+	s.mu.Lock()
+	delete(s.values, strings.ToLower(key))
+	s.mu.Unlock()
+
+	s.notify()
+}
+
+// Watch calls onChange when a key is set or deleted, until ctx is done.
+func (s *MapSource) Watch(ctx context.Context, onChange func()) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.watchers[&onChange] = ctx
+
+	return nil
+}
+
+func (s *MapSource) notify() {
+	var callbacks []func()
+
+	s.mu.Lock()
+	for fn, ctx := range s.watchers {
+		if ctx.Err() != nil {
+			delete(s.watchers, fn)
+			continue
+		}
+
+		callbacks = append(callbacks, *fn)
+	}
+	s.mu.Unlock()
+
+	for _, fn := range callbacks {
+		fn()
+	}
+}
+
+// DirSource is a Source of the files in a directory, one file per key named after the key eg. db.host,
+// like a Kubernetes ConfigMap or Secret mounted as a volume. The trailing newline of a file is trimmed.
+type DirSource struct {
+	dir string
+}
+
+// NewDirSource returns a DirSource of the files in dir.
+func NewDirSource(dir string) *DirSource {
+	return &DirSource{dir: dir}
+}
+
+// Get returns the content of the file of a key, or false if there is no file for the key.
+func (s *DirSource) Get(key string) (any, bool, error) {
+	// Hidden files eg. the ..data link of a ConfigMap volume aren't keys.
+	if key == "" || strings.HasPrefix(key, ".") || strings.ContainsAny(key, `/\`) {
+		return nil, false, nil
+	}
+
+	path := filepath.Join(s.dir, key)
+	if !isFile(path) {
+		return nil, false, nil
+	}
+
+	val, err := readSecret(path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return val, true, nil
+}
+
+// Watch calls onChange when a file is changed in the directory, until ctx is done.
+func (s *DirSource) Watch(ctx context.Context, onChange func()) error {
+	return watchDir(ctx, s.dir, func(event fsnotify.Event) bool {
+		return true
+	}, onChange)
+}
+
+// watchDir calls onChange for every event in a dir accepted by match, until ctx is done.
+func watchDir(ctx context.Context, dir string, match func(event fsnotify.Event) bool, onChange func()) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+
+	if err := watcher.Add(dir); err != nil {
+		watcher.Close()
+		return err
+	}
+
+	go func() {
+		defer watcher.Close()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+
+				if event.Has(fsnotify.Chmod) || !match(event) {
+					continue
+				}
+
+				onChange()
+			case _, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,216 @@
+package cli_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/cybersamx/golib/cli"
+)
+
+type failingSource struct{}
+
+func (failingSource) Get(key string) (any, bool, error) {
+	return nil, false, errors.New("connection refused")
+}
+
+func (failingSource) Watch(ctx context.Context, onChange func()) error {
+	return nil
+}
+
+func TestAddSource(t *testing.T) {
+	tests := []struct {
+		name       string
+		level      Origin
+		wantHost   string
+		wantPort   int
+		wantOrigin Origin
+	}{
+		{name: "Default", level: OriginDefault, wantHost: "file.local", wantPort: 2, wantOrigin: OriginFile},
+		{name: "File", level: OriginFile, wantHost: "source.local", wantPort: 2, wantOrigin: OriginSource},
+		{name: "Env", level: OriginEnv, wantHost: "source.local", wantPort: 3, wantOrigin: OriginSource},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SRC_DB_PORT", "2")
+
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			require.NoError(t, os.WriteFile(path, []byte("db:\n  host: file.local\n"), 0o600))
+
+			v := NewViper("SRC")
+			v.SetConfigFile(path)
+			require.NoError(t, v.ReadInConfig())
+
+			source := NewMapSource(map[string]any{"DB.Host": "source.local", "db.port": 3, "tags": "a,b"})
+			require.NoError(t, AddSource(v, source, tt.level))
+
+			var (
+				host string
+				port int
+				tags []string
+				user string
+			)
+
+			bindings := []FlagBinding{
+				Bind("db.host", &host, "localhost", "database host"),
+				Bind("db.port", &port, 1, "database port"),
+				Bind("tags", &tags, nil, "tags"),
+				Bind("user", &user, "admin", "user"),
+			}
+
+			flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+			require.NoError(t, InitFlags(v, flags, bindings))
+			require.NoError(t, flags.Parse([]string{"--user=root"}))
+
+			assert.Equal(t, tt.wantHost, host)
+			assert.Equal(t, tt.wantPort, port)
+			assert.Equal(t, []string{"a", "b"}, tags)
+			assert.Equal(t, "root", user)
+
+			report := EffectiveConfig(v, flags, bindings)
+			assert.Equal(t, tt.wantOrigin, report.Values[0].Origin)
+			assert.Equal(t, OriginSource, report.Values[2].Origin)
+			assert.Equal(t, OriginFlag, report.Values[3].Origin)
+		})
+	}
+}
+
+func TestAddSource_Precedence(t *testing.T) {
+	t.Parallel()
+
+	v := NewViper("")
+	require.NoError(t, AddSource(v, NewMapSource(map[string]any{"name": "first", "other": "first"}), OriginFile))
+	require.NoError(t, AddSource(v, NewMapSource(map[string]any{"name": "default"}), OriginDefault))
+	require.NoError(t, AddSource(v, NewMapSource(map[string]any{"name": "second"}), OriginFile))
+
+	var name, other string
+	bindings := []FlagBinding{
+		Bind("name", &name, "", "name"),
+		Bind("other", &other, "", "other"),
+	}
+
+	err := InitFlags(v, pflag.NewFlagSet("test", pflag.ContinueOnError), bindings)
+	require.NoError(t, err)
+	assert.Equal(t, "second", name)
+	assert.Equal(t, "first", other)
+
+	err = AddSource(v, NewMapSource(nil), OriginFlag)
+	assert.ErrorIs(t, err, ErrInvalidSourceLevel)
+}
+
+func TestAddSource_Error(t *testing.T) {
+	t.Parallel()
+
+	v := NewViper("")
+	require.NoError(t, AddSource(v, failingSource{}, OriginFile))
+
+	var name string
+	err := InitFlags(v, pflag.NewFlagSet("test", pflag.ContinueOnError), []FlagBinding{
+		Bind("name", &name, "", "name"),
+	})
+	assert.ErrorContains(t, err, "failed to get name from source: connection refused")
+}
+
+func TestDirSource(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "db.host"), []byte("db.local\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "..data"), []byte("hidden"), 0o600))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "sub"), 0o700))
+
+	source := NewDirSource(dir)
+
+	tests := []struct {
+		key    string
+		want   any
+		wantOk bool
+	}{
+		{key: "db.host", want: "db.local", wantOk: true},
+		{key: "db.port"},
+		{key: "..data"},
+		{key: "sub"},
+		{key: "../db.host"},
+	}
+
+	for _, tt := range tests {
+		val, ok, err := source.Get(tt.key)
+		require.NoError(t, err)
+		assert.Equal(t, tt.wantOk, ok, tt.key)
+		assert.Equal(t, tt.want, val, tt.key)
+	}
+
+	changed := make(chan struct{}, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	err := source.Watch(ctx, func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "db.port"), []byte("5432"), 0o600))
+
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the change")
+	}
+
+	val, ok, err := source.Get("db.port")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "5432", val)
+}
+
+func TestWatchConfig_Source(t *testing.T) {
+	t.Parallel()
+
+	v := NewViper("")
+	source := NewMapSource(map[string]any{"name": "old"})
+	require.NoError(t, AddSource(v, source, OriginFile))
+
+	var name string
+	bindings := []FlagBinding{Bind("name", &name, "", "name")}
+
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	require.NoError(t, InitFlags(v, flags, bindings))
+	require.NoError(t, flags.Parse(nil))
+	assert.Equal(t, "old", name)
+
+	watcher, err := WatchConfig(v, flags, bindings)
+	require.NoError(t, err)
+
+	changes := make(chan change, 2)
+	watcher.OnChange(func(name string, oldVal, newVal any) {
+		changes <- change{name: name, oldVal: oldVal, newVal: newVal}
+	})
+
+	source.Set("name", "new")
+	assert.Equal(t, change{name: "name", oldVal: "old", newVal: "new"}, <-changes)
+
+	source.Delete("name")
+	assert.Equal(t, change{name: "name", oldVal: "new", newVal: ""}, <-changes)
+
+	require.NoError(t, watcher.Close())
+
+	// A source isn't watched after the watcher is closed.
+	source.Set("name", "closed")
+	watcher.View(func() {
+		assert.Equal(t, "", name)
+	})
+
+	_, err = WatchConfig(NewViper(""), flags, bindings)
+	assert.ErrorIs(t, err, ErrNoConfigFile)
+}
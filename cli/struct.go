@@ -0,0 +1,154 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"unicode/utf8"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// Struct tags recognized by BindStruct.
+const (
+	tagFlag    = "flag"
+	tagShort   = "short"
+	tagUsage   = "usage"
+	tagDefault = "default"
+
+	keyDelimiter = "."
+)
+
+var ErrInvalidConfigStruct = errors.New("invalid config struct")
+
+// BindStruct derives the flag bindings from the tags of the struct pointed to by cfg and then
+// passes them to InitFlags. See StructBindings for the supported tags.
+func BindStruct(v *viper.Viper, flags *pflag.FlagSet, cfg any) error {
+	bindings, err := StructBindings(cfg)
+	if err != nil {
+		return err
+	}
+
+	return InitFlags(v, flags, bindings)
+}
+
+// StructBindings walks the struct pointed to by cfg and returns a FlagBinding for every exported
+// field with a `flag` tag. The following tags are recognized:
+//
+//	flag    - name of the flag, "-" skips the field.
+//	short   - one character shorthand of the flag.
+//	usage   - description of the flag.
+//	default - default value of the flag, parsed according to the type of the field.
+//
+// A nested struct field with a `flag` tag prefixes the names of its fields, so that field `DBURL`
+// tagged `flag:"db-url"` in a struct field tagged `flag:"store"` is bound to `store.db-url`.
+// A nested struct field without a `flag` tag and embedded structs are flattened. It returns
+// ErrInvalidConfigStruct if a tag is invalid, or if an unexported embedded field that isn't a struct
+// is tagged.
+func StructBindings(cfg any) ([]FlagBinding, error) {
+	rv := reflect.ValueOf(cfg)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("config must be a non-nil pointer to a struct, got %T: %w", cfg, ErrInvalidConfigStruct)
+	}
+
+	return structBindings(rv.Elem(), "")
+}
+
+func structBindings(rv reflect.Value, prefix string) ([]FlagBinding, error) {
+	var bindings []FlagBinding
+
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+
+		name, tagged := field.Tag.Lookup(tagFlag)
+		if name == "-" {
+			continue
+		}
+
+		if isNestedStruct(field.Type) {
+			nestedPrefix := prefix
+			if tagged && name != "" {
+				nestedPrefix = joinKey(prefix, name)
+			}
+
+			nested, err := structBindings(rv.Field(i), nestedPrefix)
+			if err != nil {
+				return nil, err
+			}
+
+			bindings = append(bindings, nested...)
+			continue
+		}
+
+		if !tagged || name == "" {
+			continue
+		}
+
+		// The value of an unexported embedded field eg. of a named string type can't be bound.
+		if !field.IsExported() {
+			return nil, fmt.Errorf("embedded field %s must be exported: %w", field.Name, ErrInvalidConfigStruct)
+		}
+
+		binding := FlagBinding{
+			Name:   joinKey(prefix, name),
+			Usage:  field.Tag.Get(tagUsage),
+			Target: rv.Field(i).Addr().Interface(),
+		}
+
+		if short := field.Tag.Get(tagShort); short != "" {
+			r, size := utf8.DecodeRuneInString(short)
+			if size != len(short) {
+				return nil, fmt.Errorf("shorthand %q of field %s must be one character: %w",
+					short, field.Name, ErrInvalidConfigStruct)
+			}
+
+			binding.Shorthand = r
+		}
+
+		if def, ok := field.Tag.Lookup(tagDefault); ok {
+			val, err := parseDefault(field.Type, def)
+			if err != nil {
+				return nil, fmt.Errorf("invalid default %q of field %s: %v: %w",
+					def, field.Name, err, ErrInvalidConfigStruct)
+			}
+
+			binding.Default = val
+		}
+
+		bindings = append(bindings, binding)
+	}
+
+	return bindings, nil
+}
+
+func isNestedStruct(typ reflect.Type) bool {
+	// Some structs eg. net.IPNet and time.Time are supported flag types.
+	if _, ok := flagTypeOf(reflect.New(typ).Interface()); ok {
+		return false
+	}
+
+	return typ.Kind() == reflect.Struct
+}
+
+func joinKey(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+
+	return prefix + keyDelimiter + name
+}
+
+// parseDefault converts the string value of a `default` tag to a value of type typ.
+func parseDefault(typ reflect.Type, str string) (any, error) {
+	ft, ok := flagTypeOf(reflect.New(typ).Interface())
+	if !ok {
+		return nil, fmt.Errorf("unsupported type %s", typ)
+	}
+
+	return ft.decode(str)
+}
@@ -0,0 +1,120 @@
+package cli_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kylelemons/godebug/pretty"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/cybersamx/golib/cli"
+)
+
+type storeConfig struct {
+	DBURL   string        `flag:"db-url" short:"d" usage:"database url" default:"postgres://localhost:5432/db"`
+	Timeout time.Duration `flag:"timeout" usage:"database timeout" default:"5s"`
+}
+
+type logConfig struct {
+	Verbose bool `flag:"verbose" short:"v" usage:"verbose logging"`
+}
+
+type embeddedName string
+
+type appConfig struct {
+	logConfig
+
+	Name     string            `flag:"name" usage:"name of the app" default:"app"`
+	Port     int               `flag:"port" short:"p" usage:"port to listen to" default:"8080"`
+	Hosts    []string          `flag:"hosts" usage:"allowed hosts" default:"localhost,127.0.0.1"`
+	Labels   map[string]string `flag:"labels" usage:"labels" default:"env=dev,team=core"`
+	Store    storeConfig       `flag:"store"`
+	Ignored  string            `flag:"-"`
+	Untagged string
+}
+
+func TestStructBindings(t *testing.T) {
+	t.Parallel()
+
+	cfg := new(appConfig)
+	bindings, err := StructBindings(cfg)
+	require.NoError(t, err)
+
+	names := make([]string, 0, len(bindings))
+	for _, binding := range bindings {
+		names = append(names, binding.Name)
+	}
+
+	want := []string{"verbose", "name", "port", "hosts", "labels", "store.db-url", "store.timeout"}
+	assert.Equal(t, want, names)
+
+	assert.Equal(t, 'd', bindings[5].Shorthand)
+	assert.Equal(t, "database url", bindings[5].Usage)
+	assert.Equal(t, &cfg.Store.DBURL, bindings[5].Target)
+	assert.Equal(t, 5*time.Second, bindings[6].Default)
+}
+
+func TestStructBindings_Invalid(t *testing.T) {
+	t.Parallel()
+
+	type badShorthand struct {
+		Name string `flag:"name" short:"nm"`
+	}
+
+	type badDefault struct {
+		Port int `flag:"port" default:"http"`
+	}
+
+	type unexportedEmbedded struct {
+		embeddedName `flag:"emb"`
+	}
+
+	inputs := []any{
+		nil,
+		appConfig{},
+		new(string),
+		&badShorthand{},
+		&badDefault{},
+		&unexportedEmbedded{},
+	}
+
+	for _, input := range inputs {
+		_, err := StructBindings(input)
+		assert.ErrorIs(t, err, ErrInvalidConfigStruct)
+	}
+}
+
+func TestBindStruct(t *testing.T) {
+	t.Setenv("GL_STORE_TIMEOUT", "10s")
+
+	cfg := new(appConfig)
+	v := NewViper("GL")
+	cmd := cobra.Command{
+		Use: "app_test",
+		Run: func(cmd *cobra.Command, args []string) {},
+	}
+
+	err := BindStruct(v, cmd.Flags(), cfg)
+	require.NoError(t, err)
+
+	cmd.SetArgs([]string{"-v", "-p", "9090", "--store.db-url=mysql://localhost:3306/db"})
+	err = cmd.Execute()
+	require.NoError(t, err)
+
+	want := appConfig{
+		logConfig: logConfig{Verbose: true},
+		Name:      "app",
+		Port:      9090,
+		Hosts:     []string{"localhost", "127.0.0.1"},
+		Labels:    map[string]string{"env": "dev", "team": "core"},
+		Store: storeConfig{
+			DBURL:   "mysql://localhost:3306/db",
+			Timeout: 10 * time.Second,
+		},
+	}
+
+	diff := pretty.Compare(want, cfg)
+	assert.Emptyf(t, diff, "want: %+v, got: %+v", want, cfg)
+}
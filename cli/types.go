@@ -0,0 +1,456 @@
+package cli
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/cast"
+	"github.com/spf13/pflag"
+)
+
+// HexBytes is a byte slice that is encoded in hex in flags, env variables and config files.
+// A *[]byte target is encoded in base64.
+type HexBytes []byte
+
+// flagType describes how to register a flag for a target type and how to decode a value read from
+// an env variable or a config file to the type.
+type flagType struct {
+	define func(flags *pflag.FlagSet, target any, name, shorthand string, def any, usage string)
+	decode func(val any) (any, error)
+}
+
+// flagTypes maps the pointer type of a FlagBinding target to its flagType.
+var flagTypes = map[reflect.Type]flagType{}
+
+// varPFunc has the signature of the pflag.FlagSet methods (as method expressions) that define a
+// flag and bind it to a variable eg. (*pflag.FlagSet).StringVarP.
+type varPFunc[T any] func(flags *pflag.FlagSet, p *T, name, shorthand string, value T, usage string)
+
+func registerFlagType[T any](varP varPFunc[T], decode func(val any) (T, error)) {
+	flagTypes[reflect.TypeOf((*T)(nil))] = flagType{
+		define: func(flags *pflag.FlagSet, target any, name, shorthand string, def any, usage string) {
+			// The default may be of a type assignable to T eg. []byte to HexBytes.
+			var value T
+			setDefault(&value, def)
+
+			varP(flags, target.(*T), name, shorthand, value, usage)
+		},
+		decode: func(val any) (any, error) {
+			return decode(val)
+		},
+	}
+}
+
+func init() {
+	registerFlagType((*pflag.FlagSet).StringVarP, cast.ToStringE)
+	registerFlagType((*pflag.FlagSet).BoolVarP, cast.ToBoolE)
+	registerFlagType((*pflag.FlagSet).IntVarP, cast.ToIntE)
+	registerFlagType((*pflag.FlagSet).Int8VarP, cast.ToInt8E)
+	registerFlagType((*pflag.FlagSet).Int16VarP, cast.ToInt16E)
+	registerFlagType((*pflag.FlagSet).Int32VarP, cast.ToInt32E)
+	registerFlagType((*pflag.FlagSet).Int64VarP, cast.ToInt64E)
+	registerFlagType((*pflag.FlagSet).UintVarP, cast.ToUintE)
+	registerFlagType((*pflag.FlagSet).Uint8VarP, cast.ToUint8E)
+	registerFlagType((*pflag.FlagSet).Uint16VarP, cast.ToUint16E)
+	registerFlagType((*pflag.FlagSet).Uint32VarP, cast.ToUint32E)
+	registerFlagType((*pflag.FlagSet).Uint64VarP, cast.ToUint64E)
+	registerFlagType((*pflag.FlagSet).Float32VarP, cast.ToFloat32E)
+	registerFlagType((*pflag.FlagSet).Float64VarP, cast.ToFloat64E)
+	registerFlagType((*pflag.FlagSet).DurationVarP, cast.ToDurationE)
+	registerFlagType((*pflag.FlagSet).IPVarP, toIP)
+	registerFlagType((*pflag.FlagSet).IPNetVarP, toIPNet)
+	registerFlagType((*pflag.FlagSet).BytesBase64VarP, toBytesBase64)
+	registerFlagType(bytesHexVarP, toBytesHex)
+
+	registerFlagType((*pflag.FlagSet).StringSliceVarP, toSliceE(cast.ToStringE))
+	registerFlagType((*pflag.FlagSet).BoolSliceVarP, toSliceE(cast.ToBoolE))
+	registerFlagType((*pflag.FlagSet).IntSliceVarP, toSliceE(cast.ToIntE))
+	registerFlagType((*pflag.FlagSet).Int32SliceVarP, toSliceE(cast.ToInt32E))
+	registerFlagType((*pflag.FlagSet).Int64SliceVarP, toSliceE(cast.ToInt64E))
+	registerFlagType((*pflag.FlagSet).UintSliceVarP, toSliceE(cast.ToUintE))
+	registerFlagType((*pflag.FlagSet).Float32SliceVarP, toSliceE(cast.ToFloat32E))
+	registerFlagType((*pflag.FlagSet).Float64SliceVarP, toSliceE(cast.ToFloat64E))
+	registerFlagType((*pflag.FlagSet).DurationSliceVarP, toSliceE(cast.ToDurationE))
+	registerFlagType((*pflag.FlagSet).IPSliceVarP, toSliceE(toIP))
+
+	registerFlagType((*pflag.FlagSet).StringToStringVarP, toMapE(cast.ToStringE))
+	registerFlagType((*pflag.FlagSet).StringToIntVarP, toMapE(cast.ToIntE))
+	registerFlagType((*pflag.FlagSet).StringToInt64VarP, toMapE(cast.ToInt64E))
+}
+
+func bytesHexVarP(flags *pflag.FlagSet, p *HexBytes, name, shorthand string, value HexBytes, usage string) {
+	flags.BytesHexVarP((*[]byte)(p), name, shorthand, value, usage)
+}
+
+func toIP(val any) (net.IP, error) {
+	if ip, ok := val.(net.IP); ok {
+		return ip, nil
+	}
+
+	str, err := cast.ToStringE(val)
+	if err != nil {
+		return nil, err
+	}
+
+	ip := net.ParseIP(strings.TrimSpace(str))
+	if ip == nil {
+		return nil, fmt.Errorf("invalid ip address %q", str)
+	}
+
+	return ip, nil
+}
+
+func toIPNet(val any) (net.IPNet, error) {
+	if ipNet, ok := val.(net.IPNet); ok {
+		return ipNet, nil
+	}
+
+	str, err := cast.ToStringE(val)
+	if err != nil {
+		return net.IPNet{}, err
+	}
+
+	_, ipNet, err := net.ParseCIDR(strings.TrimSpace(str))
+	if err != nil {
+		return net.IPNet{}, err
+	}
+
+	return *ipNet, nil
+}
+
+func toBytesBase64(val any) ([]byte, error) {
+	if b, ok := val.([]byte); ok {
+		return b, nil
+	}
+
+	str, err := cast.ToStringE(val)
+	if err != nil {
+		return nil, err
+	}
+
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(str))
+}
+
+func toBytesHex(val any) (HexBytes, error) {
+	if b, ok := val.([]byte); ok {
+		return b, nil
+	}
+
+	str, err := cast.ToStringE(val)
+	if err != nil {
+		return nil, err
+	}
+
+	return hex.DecodeString(strings.TrimSpace(str))
+}
+
+// toSliceE returns a function that converts a value to a slice, casting every item with castE.
+//
+// An environment variable, unlike a flag, is a singleton. Any subsequent set env will just override
+// the previous value. So the value of a slice set in an env variable is encoded in csv
+// item1,item2,item3 format. We may have comma in the value as long as it is enclosed by "" - standard
+// csv. A value enclosed by [] is decoded as a json array instead. A config file on the other hand
+// yields a list, which is cast item by item.
+func toSliceE[T any](castE func(val any) (T, error)) func(val any) ([]T, error) {
+	return func(val any) ([]T, error) {
+		var items []any
+
+		switch typed := val.(type) {
+		case []T:
+			return typed, nil
+		case string:
+			str := strings.TrimSpace(typed)
+			if str == "" {
+				return []T{}, nil
+			}
+
+			if strings.HasPrefix(str, "[") && strings.HasSuffix(str, "]") {
+				if err := json.Unmarshal([]byte(str), &items); err != nil {
+					return nil, fmt.Errorf("invalid json array: %w", err)
+				}
+
+				break
+			}
+
+			fields, err := csv.NewReader(strings.NewReader(typed)).Read()
+			if err != nil {
+				return nil, fmt.Errorf("invalid csv: %w", err)
+			}
+
+			for _, field := range fields {
+				items = append(items, field)
+			}
+		default:
+			rv := reflect.ValueOf(val)
+			if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+				return nil, fmt.Errorf("unable to cast %#v of type %T to a slice", val, val)
+			}
+
+			for i := 0; i < rv.Len(); i++ {
+				items = append(items, rv.Index(i).Interface())
+			}
+		}
+
+		slice := make([]T, 0, len(items))
+		for _, item := range items {
+			t, err := castE(item)
+			if err != nil {
+				return nil, err
+			}
+
+			slice = append(slice, t)
+		}
+
+		return slice, nil
+	}
+}
+
+// toMapE returns a function that converts a value to a map, casting every value with castE.
+//
+// For env, viper returns a string (not a map) so we decode the string. The value can be encoded in
+// json {"key": "value"} format or in key1=value1,key2=value2 format, see parsePairs.
+func toMapE[T any](castE func(val any) (T, error)) func(val any) (map[string]T, error) {
+	return func(val any) (map[string]T, error) {
+		var items map[string]any
+
+		switch typed := val.(type) {
+		case map[string]T:
+			return typed, nil
+		case string:
+			items = map[string]any{}
+			str := strings.TrimSpace(typed)
+			if str == "" {
+				break
+			}
+
+			if strings.HasPrefix(str, "{") {
+				if err := json.Unmarshal([]byte(str), &items); err != nil {
+					return nil, fmt.Errorf("invalid json object: %w", err)
+				}
+
+				break
+			}
+
+			pairs, err := parsePairs(str)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, pair := range pairs {
+				items[pair[0]] = pair[1]
+			}
+		default:
+			m, err := cast.ToStringMapE(val)
+			if err != nil {
+				return nil, err
+			}
+
+			items = m
+		}
+
+		m := make(map[string]T, len(items))
+		for key, item := range items {
+			t, err := castE(item)
+			if err != nil {
+				return nil, err
+			}
+
+			m[key] = t
+		}
+
+		return m, nil
+	}
+}
+
+// parsePairs parses pairs in key1=value1,key2=value2 format. A pair is split at the first =, so a value
+// may contain =. A backslash escapes a comma, an equal sign or a backslash eg. a\=b=c\,d is the key a=b
+// with the value c,d. Any other backslash is kept as is.
+func parsePairs(str string) ([][2]string, error) {
+	var (
+		pairs    [][2]string
+		sb       strings.Builder
+		key      string
+		hasKey   bool
+		escaped  bool
+		startPos int
+	)
+
+	endPair := func(pos int) error {
+		if !hasKey {
+			if sb.Len() == 0 {
+				// Skip an empty pair eg. a trailing comma.
+				return nil
+			}
+
+			return fmt.Errorf("pair %q at position %d must be formatted as key=value", str[startPos:pos], startPos)
+		}
+
+		pairs = append(pairs, [2]string{key, sb.String()})
+		sb.Reset()
+		hasKey = false
+
+		return nil
+	}
+
+	for pos, r := range str {
+		switch {
+		case escaped:
+			if r != ',' && r != '=' && r != '\\' {
+				sb.WriteRune('\\')
+			}
+
+			sb.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '=' && !hasKey:
+			key = sb.String()
+			sb.Reset()
+			hasKey = true
+		case r == ',':
+			if err := endPair(pos); err != nil {
+				return nil, err
+			}
+
+			startPos = pos + 1
+		default:
+			sb.WriteRune(r)
+		}
+	}
+
+	if escaped {
+		sb.WriteRune('\\')
+	}
+
+	if err := endPair(len(str)); err != nil {
+		return nil, err
+	}
+
+	return pairs, nil
+}
+
+// flagTypeOf returns the flagType of a FlagBinding target. A target that isn't one of the registered
+// types is supported if it implements pflag.Value or encoding.TextUnmarshaler.
+func flagTypeOf(target any) (flagType, bool) {
+	typ := reflect.TypeOf(target)
+	if ft, ok := flagTypes[typ]; ok {
+		return ft, true
+	}
+
+	switch target.(type) {
+	case pflag.Value:
+		return flagType{
+			define: func(flags *pflag.FlagSet, target any, name, shorthand string, def any, usage string) {
+				setDefault(target, def)
+				flags.VarP(target.(pflag.Value), name, shorthand, usage)
+			},
+			decode: textDecoder(typ),
+		}, true
+	case encoding.TextUnmarshaler:
+		return flagType{
+			define: func(flags *pflag.FlagSet, target any, name, shorthand string, def any, usage string) {
+				setDefault(target, def)
+				flags.VarP(&textValue{target: target.(encoding.TextUnmarshaler)}, name, shorthand, usage)
+			},
+			decode: textDecoder(typ),
+		}, true
+	}
+
+	return flagType{}, false
+}
+
+func setDefault(target any, def any) {
+	if def != nil {
+		reflect.ValueOf(target).Elem().Set(reflect.ValueOf(def))
+	}
+}
+
+// textDecoder returns a function that decodes a value to the type pointed to by typ, which implements
+// encoding.TextUnmarshaler or pflag.Value. If typ implements both, UnmarshalText is used.
+func textDecoder(typ reflect.Type) func(val any) (any, error) {
+	return func(val any) (any, error) {
+		if reflect.TypeOf(val) == typ.Elem() {
+			return val, nil
+		}
+
+		str, err := cast.ToStringE(val)
+		if err != nil {
+			return nil, err
+		}
+
+		ptr := reflect.New(typ.Elem())
+		switch target := ptr.Interface().(type) {
+		case encoding.TextUnmarshaler:
+			err = target.UnmarshalText([]byte(str))
+		case pflag.Value:
+			err = target.Set(str)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return ptr.Elem().Interface(), nil
+	}
+}
+
+// textValue adapts an encoding.TextUnmarshaler to a pflag.Value.
+type textValue struct {
+	target encoding.TextUnmarshaler
+}
+
+func (t *textValue) Set(str string) error {
+	return t.target.UnmarshalText([]byte(str))
+}
+
+func (t *textValue) String() string {
+	if t.target == nil {
+		return ""
+	}
+
+	if marshaler, ok := t.target.(encoding.TextMarshaler); ok {
+		if text, err := marshaler.MarshalText(); err == nil {
+			return string(text)
+		}
+	}
+
+	return fmt.Sprint(reflect.ValueOf(t.target).Elem().Interface())
+}
+
+func (t *textValue) Type() string {
+	name := reflect.TypeOf(t.target).Elem().Name()
+	if name == "" {
+		return "value"
+	}
+
+	return strings.ToLower(name)
+}
+
+// decodeValue decodes val to the type of the variable pointed to by target.
+func decodeValue(target any, val any) (any, error) {
+	ft, ok := flagTypeOf(target)
+	if !ok {
+		return nil, fmt.Errorf("unsupported target type %T", target)
+	}
+
+	return ft.decode(val)
+}
+
+// setTarget decodes val and assigns the result to the variable pointed to by target.
+func setTarget(target any, val any) error {
+	decoded, err := decodeValue(target, val)
+	if err != nil {
+		return err
+	}
+
+	reflect.ValueOf(target).Elem().Set(reflect.ValueOf(decoded))
+
+	return nil
+}
@@ -0,0 +1,543 @@
+package cli_test
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cybersamx/golib/reflectutils"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/cybersamx/golib/cli"
+)
+
+func mustParseCIDR(t *testing.T, cidr string) net.IPNet {
+	t.Helper()
+
+	_, ipNet, err := net.ParseCIDR(cidr)
+	require.NoError(t, err)
+
+	return *ipNet
+}
+
+func TestInitFlags_Types(t *testing.T) {
+	tests := []struct {
+		description string
+		newTarget   func() any // Returns a pointer to a new target.
+		def         any
+		env         string
+		config      string // Value of the key `arg` in a yaml config file.
+		flag        string
+		wantDefault any
+		wantEnv     any
+		wantConfig  any
+		wantFlag    any
+	}{
+		{
+			description: "int8",
+			newTarget:   func() any { return new(int8) },
+			def:         int8(1),
+			env:         "2",
+			config:      "3",
+			flag:        "4",
+			wantDefault: int8(1), wantEnv: int8(2), wantConfig: int8(3), wantFlag: int8(4),
+		},
+		{
+			description: "int16",
+			newTarget:   func() any { return new(int16) },
+			def:         int16(1),
+			env:         "2",
+			config:      "3",
+			flag:        "4",
+			wantDefault: int16(1), wantEnv: int16(2), wantConfig: int16(3), wantFlag: int16(4),
+		},
+		{
+			description: "int32",
+			newTarget:   func() any { return new(int32) },
+			def:         int32(1),
+			env:         "2",
+			config:      "3",
+			flag:        "4",
+			wantDefault: int32(1), wantEnv: int32(2), wantConfig: int32(3), wantFlag: int32(4),
+		},
+		{
+			description: "int64",
+			newTarget:   func() any { return new(int64) },
+			def:         int64(1),
+			env:         "2",
+			config:      "3",
+			flag:        "4",
+			wantDefault: int64(1), wantEnv: int64(2), wantConfig: int64(3), wantFlag: int64(4),
+		},
+		{
+			description: "uint",
+			newTarget:   func() any { return new(uint) },
+			def:         uint(1),
+			env:         "2",
+			config:      "3",
+			flag:        "4",
+			wantDefault: uint(1), wantEnv: uint(2), wantConfig: uint(3), wantFlag: uint(4),
+		},
+		{
+			description: "uint8",
+			newTarget:   func() any { return new(uint8) },
+			def:         uint8(1),
+			env:         "2",
+			config:      "3",
+			flag:        "4",
+			wantDefault: uint8(1), wantEnv: uint8(2), wantConfig: uint8(3), wantFlag: uint8(4),
+		},
+		{
+			description: "uint16",
+			newTarget:   func() any { return new(uint16) },
+			def:         uint16(1),
+			env:         "2",
+			config:      "3",
+			flag:        "4",
+			wantDefault: uint16(1), wantEnv: uint16(2), wantConfig: uint16(3), wantFlag: uint16(4),
+		},
+		{
+			description: "uint32",
+			newTarget:   func() any { return new(uint32) },
+			def:         uint32(1),
+			env:         "2",
+			config:      "3",
+			flag:        "4",
+			wantDefault: uint32(1), wantEnv: uint32(2), wantConfig: uint32(3), wantFlag: uint32(4),
+		},
+		{
+			description: "uint64",
+			newTarget:   func() any { return new(uint64) },
+			def:         uint64(1),
+			env:         "2",
+			config:      "3",
+			flag:        "4",
+			wantDefault: uint64(1), wantEnv: uint64(2), wantConfig: uint64(3), wantFlag: uint64(4),
+		},
+		{
+			description: "float32",
+			newTarget:   func() any { return new(float32) },
+			def:         float32(1.5),
+			env:         "2.5",
+			config:      "3.5",
+			flag:        "4.5",
+			wantDefault: float32(1.5), wantEnv: float32(2.5), wantConfig: float32(3.5), wantFlag: float32(4.5),
+		},
+		{
+			description: "float64",
+			newTarget:   func() any { return new(float64) },
+			def:         1.5,
+			env:         "2.5",
+			config:      "3.5",
+			flag:        "4.5",
+			wantDefault: 1.5, wantEnv: 2.5, wantConfig: 3.5, wantFlag: 4.5,
+		},
+		{
+			description: "[]int",
+			newTarget:   func() any { return new([]int) },
+			def:         []int{1},
+			env:         "2,3",
+			config:      "[3, 4]",
+			flag:        "4,5",
+			wantDefault: []int{1}, wantEnv: []int{2, 3}, wantConfig: []int{3, 4}, wantFlag: []int{4, 5},
+		},
+		{
+			description: "[]bool",
+			newTarget:   func() any { return new([]bool) },
+			def:         []bool{true},
+			env:         "false,true",
+			config:      "[true, false]",
+			flag:        "false,false",
+			wantDefault: []bool{true},
+			wantEnv:     []bool{false, true},
+			wantConfig:  []bool{true, false},
+			wantFlag:    []bool{false, false},
+		},
+		{
+			description: "[]time.Duration",
+			newTarget:   func() any { return new([]time.Duration) },
+			def:         []time.Duration{time.Second},
+			env:         "2s,3m",
+			config:      "[3s, 4m]",
+			flag:        "4s,5m",
+			wantDefault: []time.Duration{time.Second},
+			wantEnv:     []time.Duration{2 * time.Second, 3 * time.Minute},
+			wantConfig:  []time.Duration{3 * time.Second, 4 * time.Minute},
+			wantFlag:    []time.Duration{4 * time.Second, 5 * time.Minute},
+		},
+		{
+			description: "[]float64",
+			newTarget:   func() any { return new([]float64) },
+			def:         []float64{1.5},
+			env:         "2.5,3.5",
+			config:      "[3.5, 4.5]",
+			flag:        "4.5,5.5",
+			wantDefault: []float64{1.5},
+			wantEnv:     []float64{2.5, 3.5},
+			wantConfig:  []float64{3.5, 4.5},
+			wantFlag:    []float64{4.5, 5.5},
+		},
+		{
+			description: "map[string]int",
+			newTarget:   func() any { return new(map[string]int) },
+			def:         map[string]int{"a": 1},
+			env:         `{"b": 2, "c": 3}`,
+			config:      "{c: 3}",
+			flag:        "d=4",
+			wantDefault: map[string]int{"a": 1},
+			wantEnv:     map[string]int{"b": 2, "c": 3},
+			wantConfig:  map[string]int{"c": 3},
+			wantFlag:    map[string]int{"d": 4},
+		},
+		{
+			description: "net.IP",
+			newTarget:   func() any { return new(net.IP) },
+			def:         net.ParseIP("127.0.0.1"),
+			env:         "10.0.0.2",
+			config:      "10.0.0.3",
+			flag:        "::1",
+			wantDefault: net.ParseIP("127.0.0.1"),
+			wantEnv:     net.ParseIP("10.0.0.2"),
+			wantConfig:  net.ParseIP("10.0.0.3"),
+			wantFlag:    net.ParseIP("::1"),
+		},
+		{
+			description: "net.IPNet",
+			newTarget:   func() any { return new(net.IPNet) },
+			def:         mustParseCIDR(t, "127.0.0.0/8"),
+			env:         "10.0.0.0/8",
+			config:      "192.168.0.0/16",
+			flag:        "172.16.0.0/12",
+			wantDefault: mustParseCIDR(t, "127.0.0.0/8"),
+			wantEnv:     mustParseCIDR(t, "10.0.0.0/8"),
+			wantConfig:  mustParseCIDR(t, "192.168.0.0/16"),
+			wantFlag:    mustParseCIDR(t, "172.16.0.0/12"),
+		},
+		{
+			description: "[]byte",
+			newTarget:   func() any { return new([]byte) },
+			def:         []byte("default"),
+			env:         "ZW52",
+			config:      "Y29uZmln",
+			flag:        "ZmxhZw==",
+			wantDefault: []byte("default"),
+			wantEnv:     []byte("env"),
+			wantConfig:  []byte("config"),
+			wantFlag:    []byte("flag"),
+		},
+		{
+			description: "HexBytes",
+			newTarget:   func() any { return new(HexBytes) },
+			def:         HexBytes("default"),
+			env:         "656e76",
+			config:      "636f6e666967",
+			flag:        "666c6167",
+			wantDefault: HexBytes("default"),
+			wantEnv:     HexBytes("env"),
+			wantConfig:  HexBytes("config"),
+			wantFlag:    HexBytes("flag"),
+		},
+	}
+
+	run := func(t *testing.T, target any, def any, config string, args []string) {
+		t.Helper()
+
+		v := NewViper("GL")
+		if config != "" {
+			v.SetConfigType("yaml")
+			err := v.ReadConfig(bytes.NewBufferString("arg: " + config))
+			require.NoError(t, err)
+		}
+
+		cmd := cobra.Command{
+			Use: "app_test",
+			Run: func(cmd *cobra.Command, args []string) {},
+		}
+
+		bindings := []FlagBinding{
+			{Usage: "test arg", Name: "arg", Target: target, Default: def},
+		}
+
+		err := InitFlags(v, cmd.Flags(), bindings)
+		require.NoError(t, err)
+
+		cmd.SetArgs(args)
+		err = cmd.Execute()
+		require.NoError(t, err)
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			target := test.newTarget()
+			run(t, target, test.def, "", nil)
+			assert.Equal(t, test.wantDefault, reflectutils.Indirect(target))
+
+			target = test.newTarget()
+			run(t, target, test.def, test.config, nil)
+			assert.Equal(t, test.wantConfig, reflectutils.Indirect(target))
+
+			target = test.newTarget()
+			run(t, target, test.def, test.config, []string{"--arg=" + test.flag})
+			assert.Equal(t, test.wantFlag, reflectutils.Indirect(target))
+
+			t.Setenv("GL_ARG", test.env)
+			target = test.newTarget()
+			run(t, target, test.def, test.config, nil)
+			assert.Equal(t, test.wantEnv, reflectutils.Indirect(target))
+		})
+	}
+}
+
+// byteSize is a custom type that implements encoding.TextUnmarshaler but not pflag.Value.
+type byteSize int64
+
+var byteUnits = []struct {
+	suffix     string
+	multiplier byteSize
+}{
+	{"KB", 1 << 10},
+	{"MB", 1 << 20},
+	{"B", 1},
+}
+
+func (b *byteSize) UnmarshalText(text []byte) error {
+	str := string(text)
+	for _, unit := range byteUnits {
+		num, found := strings.CutSuffix(str, unit.suffix)
+		if !found {
+			continue
+		}
+
+		n, err := strconv.ParseInt(num, 10, 64)
+		if err != nil {
+			return err
+		}
+
+		*b = byteSize(n) * unit.multiplier
+		return nil
+	}
+
+	return fmt.Errorf("unknown byte size %s", str)
+}
+
+func (b byteSize) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%dB", b)), nil
+}
+
+func TestInitFlags_CustomTypes(t *testing.T) {
+	type customVars struct {
+		level Level      // Implements pflag.Value.
+		size  byteSize   // Implements encoding.TextUnmarshaler.
+		addr  netip.Addr // Implements encoding.TextUnmarshaler.
+	}
+
+	tests := []struct {
+		description string
+		env         map[string]string
+		config      string
+		args        []string
+		want        customVars
+		wantErr     bool
+	}{
+		{
+			description: "Defaults",
+			want:        customVars{level: Warn, size: 2 << 10, addr: netip.MustParseAddr("127.0.0.1")},
+		},
+		{
+			description: "Config file",
+			config:      "level: error\nsize: 3MB\naddr: 10.0.0.1",
+			want:        customVars{level: Error, size: 3 << 20, addr: netip.MustParseAddr("10.0.0.1")},
+		},
+		{
+			description: "Env",
+			env:         map[string]string{"GL_LEVEL": "info", "GL_SIZE": "4KB", "GL_ADDR": "::1"},
+			config:      "level: error\nsize: 3MB\naddr: 10.0.0.1",
+			want:        customVars{level: Info, size: 4 << 10, addr: netip.MustParseAddr("::1")},
+		},
+		{
+			description: "Flags",
+			env:         map[string]string{"GL_LEVEL": "info", "GL_SIZE": "4KB", "GL_ADDR": "::1"},
+			args:        []string{"--level=fatal", "--size=5B", "--addr=10.0.0.2"},
+			want:        customVars{level: Fatal, size: 5, addr: netip.MustParseAddr("10.0.0.2")},
+		},
+		{
+			description: "Invalid flag",
+			args:        []string{"--size=5XB"},
+			wantErr:     true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			for key, val := range test.env {
+				t.Setenv(key, val)
+			}
+
+			var target customVars
+			bindings := []FlagBinding{
+				{Usage: "log level", Name: "level", Target: &target.level, Default: Warn},
+				{Usage: "byte size", Name: "size", Target: &target.size, Default: byteSize(2 << 10)},
+				{Usage: "ip address", Name: "addr", Target: &target.addr, Default: netip.MustParseAddr("127.0.0.1")},
+			}
+
+			v := NewViper("GL")
+			if test.config != "" {
+				v.SetConfigType("yaml")
+				err := v.ReadConfig(bytes.NewBufferString(test.config))
+				require.NoError(t, err)
+			}
+
+			cmd := cobra.Command{
+				Use: "app_test",
+				Run: func(cmd *cobra.Command, args []string) {},
+			}
+
+			err := InitFlags(v, cmd.Flags(), bindings)
+			require.NoError(t, err)
+
+			cmd.SetArgs(test.args)
+			err = cmd.Execute()
+			if test.wantErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.want, target)
+		})
+	}
+}
+
+func TestInitFlags_EnvEncoding(t *testing.T) {
+	tests := []struct {
+		description string
+		newTarget   func() any
+		envs        map[string]string
+		want        any
+		wantErr     string // Env variable named in the decode error.
+	}{
+		{
+			description: "Slice in csv with quotes",
+			newTarget:   func() any { return new([]string) },
+			envs:        map[string]string{"GL_ARG": `a,"b,c",d`},
+			want:        []string{"a", "b,c", "d"},
+		},
+		{
+			description: "Slice in json",
+			newTarget:   func() any { return new([]string) },
+			envs:        map[string]string{"GL_ARG": `["a", "b,c"]`},
+			want:        []string{"a", "b,c"},
+		},
+		{
+			description: "Int slice in json",
+			newTarget:   func() any { return new([]int) },
+			envs:        map[string]string{"GL_ARG": `[1, 2, 3]`},
+			want:        []int{1, 2, 3},
+		},
+		{
+			description: "Indexed slice",
+			newTarget:   func() any { return new([]string) },
+			envs:        map[string]string{"GL_ARG_0": "a", "GL_ARG_1": "b,c", "GL_ARG_3": "skipped"},
+			want:        []string{"a", "b,c"},
+		},
+		{
+			description: "Indexed int slice",
+			newTarget:   func() any { return new([]int) },
+			envs:        map[string]string{"GL_ARG_0": "1", "GL_ARG_1": "2"},
+			want:        []int{1, 2},
+		},
+		{
+			description: "Env variable takes precedence over indexed",
+			newTarget:   func() any { return new([]string) },
+			envs:        map[string]string{"GL_ARG": "a", "GL_ARG_0": "b"},
+			want:        []string{"a"},
+		},
+		{
+			description: "Indexed env variables ignored for a scalar",
+			newTarget:   func() any { return new(int) },
+			envs:        map[string]string{"GL_ARG_0": "x"},
+			want:        0,
+		},
+		{
+			description: "Indexed env variables ignored for a byte slice",
+			newTarget:   func() any { return new([]byte) },
+			envs:        map[string]string{"GL_ARG_0": "x"},
+			want:        []byte(nil),
+		},
+		{
+			description: "Map with = in value",
+			newTarget:   func() any { return new(map[string]string) },
+			envs:        map[string]string{"GL_ARG": "dsn=host=db user=app,mode=ro"},
+			want:        map[string]string{"dsn": "host=db user=app", "mode": "ro"},
+		},
+		{
+			description: "Map with escapes",
+			newTarget:   func() any { return new(map[string]string) },
+			envs:        map[string]string{"GL_ARG": `a\=b=c\,d,path=C:\dir\\,`},
+			want:        map[string]string{"a=b": "c,d", "path": `C:\dir\`},
+		},
+		{
+			description: "Int map",
+			newTarget:   func() any { return new(map[string]int) },
+			envs:        map[string]string{"GL_ARG": "a=1,b=2"},
+			want:        map[string]int{"a": 1, "b": 2},
+		},
+		{
+			description: "Map in json",
+			newTarget:   func() any { return new(map[string]string) },
+			envs:        map[string]string{"GL_ARG": `{"a": "1,2"}`},
+			want:        map[string]string{"a": "1,2"},
+		},
+		{
+			description: "Invalid pair",
+			newTarget:   func() any { return new(map[string]string) },
+			envs:        map[string]string{"GL_ARG": "a=1,b"},
+			wantErr:     "GL_ARG",
+		},
+		{
+			description: "Invalid json",
+			newTarget:   func() any { return new([]string) },
+			envs:        map[string]string{"GL_ARG": `["a", ]`},
+			wantErr:     "GL_ARG",
+		},
+		{
+			description: "Invalid csv",
+			newTarget:   func() any { return new([]string) },
+			envs:        map[string]string{"GL_ARG": `a,"b`},
+			wantErr:     "GL_ARG",
+		},
+		{
+			description: "Invalid indexed item",
+			newTarget:   func() any { return new([]int) },
+			envs:        map[string]string{"GL_ARG_0": "1", "GL_ARG_1": "two"},
+			wantErr:     "GL_ARG_1",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			for key, val := range test.envs {
+				t.Setenv(key, val)
+			}
+
+			target := test.newTarget()
+			cmd := cobra.Command{Run: func(cmd *cobra.Command, args []string) {}}
+			err := InitFlags(NewViper("GL"), cmd.Flags(), []FlagBinding{{Name: "arg", Target: target}})
+			if test.wantErr != "" {
+				assert.ErrorIs(t, err, ErrDecodeValue)
+				assert.ErrorContains(t, err, "from env "+test.wantErr+":")
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, test.want, reflectutils.Indirect(target))
+		})
+	}
+}
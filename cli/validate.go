@@ -0,0 +1,284 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+var (
+	ErrInvalidValue    = errors.New("invalid flag value")
+	ErrRequired        = errors.New("value is required")
+	ErrNotInEnum       = errors.New("value is not one of the allowed values")
+	ErrOutOfRange      = errors.New("value is out of range")
+	ErrPatternMismatch = errors.New("value doesn't match the pattern")
+	ErrInvalidRule     = errors.New("invalid validation rule")
+)
+
+// ValidationError is a violation of a validation rule of a FlagBinding. It matches ErrInvalidValue and
+// the cause Err with errors.Is.
+type ValidationError struct {
+	Name   string
+	Origin Origin // Where the offending value comes from.
+	Value  any
+	Err    error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("validationError - flag=%s; source=%s; value=%v; root_err=%v; %v",
+		e.Name, e.Origin, e.Value, e.Err, ErrInvalidValue)
+}
+
+func (e *ValidationError) Unwrap() []error {
+	return []error{e.Err, ErrInvalidValue}
+}
+
+// ValidationErrors lists every violation found by ValidateFlags.
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+
+	return strings.Join(msgs, "\n")
+}
+
+func (e ValidationErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, err := range e {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
+
+// ValidateFlags checks the values bound by InitFlags against the validation rules of the bindings.
+// Call it after the flags are parsed, when the values from the flags, env variables, config file and
+// defaults are all merged eg. in the PreRunE of a cobra command. It returns ValidationErrors listing
+// every violation.
+func ValidateFlags(v *viper.Viper, flags *pflag.FlagSet, bindings []FlagBinding) error {
+	var errs ValidationErrors
+
+	for _, binding := range bindings {
+		rv := reflect.ValueOf(binding.Target)
+		if rv.Kind() != reflect.Pointer || rv.IsNil() {
+			continue
+		}
+
+		val := rv.Elem().Interface()
+		origin := bindingOrigin(v, flags, &binding)
+
+		for _, err := range validateValue(&binding, val, origin) {
+			verr := ValidationError{
+				Name:   binding.Name,
+				Origin: origin,
+				Value:  val,
+				Err:    err,
+			}
+
+			if isSecret(&binding) {
+				verr.Value = Secret(fmt.Sprint(val))
+			}
+
+			errs = append(errs, &verr)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func validateValue(binding *FlagBinding, val any, origin Origin) []error {
+	var errs []error
+
+	if binding.Required && origin == OriginDefault {
+		errs = append(errs, ErrRequired)
+	}
+
+	if len(binding.Enum) > 0 {
+		enum, err := enumValues(binding)
+		if err == nil {
+			err = checkEnum(val, enum, isSecret(binding))
+		}
+
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if binding.Min != nil || binding.Max != nil {
+		if err := checkRange(val, binding.Min, binding.Max, isSecret(binding)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if binding.Pattern != "" {
+		if err := checkPattern(val, binding.Pattern, isSecret(binding)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if binding.Validate != nil {
+		if err := binding.Validate(val); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+// enumValues returns the allowed values of a binding converted to the type of its target, or of the
+// items of a slice target, eg. the int 1 to int64. It returns ErrInvalidRule if a value can't be
+// converted.
+func enumValues(binding *FlagBinding) ([]any, error) {
+	target := binding.Target
+	if typ := reflect.TypeOf(target).Elem(); typ.Kind() == reflect.Slice && typ.Elem().Kind() != reflect.Uint8 {
+		target = reflect.New(typ.Elem()).Interface()
+	}
+
+	// The values of a target decoded by a custom parser are compared as is.
+	if _, ok := flagTypeOf(target); !ok {
+		return binding.Enum, nil
+	}
+
+	enum := make([]any, 0, len(binding.Enum))
+	for _, item := range binding.Enum {
+		val, err := decodeValue(target, item)
+		if err != nil {
+			return nil, fmt.Errorf("enum value %v for target %T: %w", item, binding.Target, ErrInvalidRule)
+		}
+
+		enum = append(enum, val)
+	}
+
+	return enum, nil
+}
+
+func checkEnum(val any, enum []any, secret bool) error {
+	inEnum := func(item any) bool {
+		for _, allowed := range enum {
+			if reflect.DeepEqual(item, allowed) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	for _, item := range items(val) {
+		if !inEnum(item) {
+			return fmt.Errorf("%v not in %v: %w", describe(item, secret), enum, ErrNotInEnum)
+		}
+	}
+
+	return nil
+}
+
+func checkRange(val any, minVal, maxVal any, secret bool) error {
+	desc := describe(val, secret)
+	num, ok := toFloat64(val)
+	if !ok {
+		rv := reflect.ValueOf(val)
+		switch rv.Kind() {
+		case reflect.String, reflect.Slice, reflect.Map:
+			num = float64(rv.Len())
+			desc = fmt.Sprintf("length %d", rv.Len())
+		default:
+			return fmt.Errorf("min and max don't apply to %T: %w", val, ErrInvalidRule)
+		}
+	}
+
+	if minVal != nil {
+		minNum, ok := toFloat64(minVal)
+		if !ok {
+			return fmt.Errorf("min %v must be a number: %w", minVal, ErrInvalidRule)
+		}
+
+		if num < minNum {
+			return fmt.Errorf("%v is less than %v: %w", desc, minVal, ErrOutOfRange)
+		}
+	}
+
+	if maxVal != nil {
+		maxNum, ok := toFloat64(maxVal)
+		if !ok {
+			return fmt.Errorf("max %v must be a number: %w", maxVal, ErrInvalidRule)
+		}
+
+		if num > maxNum {
+			return fmt.Errorf("%v is greater than %v: %w", desc, maxVal, ErrOutOfRange)
+		}
+	}
+
+	return nil
+}
+
+func checkPattern(val any, pattern string, secret bool) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return fmt.Errorf("pattern %s: %v: %w", pattern, err, ErrInvalidRule)
+	}
+
+	for _, item := range items(val) {
+		str, ok := item.(string)
+		if !ok {
+			return fmt.Errorf("pattern doesn't apply to %T: %w", item, ErrInvalidRule)
+		}
+
+		if !re.MatchString(str) {
+			return fmt.Errorf("%v doesn't match %s: %w", describe(str, secret), pattern, ErrPatternMismatch)
+		}
+	}
+
+	return nil
+}
+
+// describe returns a value as is to be printed in an error, or masked if it's a secret.
+func describe(val any, secret bool) any {
+	if secret {
+		return maskedValue
+	}
+
+	return val
+}
+
+// items returns the items of a slice (but not a byte slice) or the value itself in a slice.
+func items(val any) []any {
+	rv := reflect.ValueOf(val)
+	if rv.Kind() != reflect.Slice || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return []any{val}
+	}
+
+	items := make([]any, 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		items = append(items, rv.Index(i).Interface())
+	}
+
+	return items
+}
+
+// toFloat64 converts a value of any numeric kind, including named types such as time.Duration, to
+// float64.
+func toFloat64(val any) (float64, bool) {
+	rv := reflect.ValueOf(val)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,164 @@
+package cli_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/cybersamx/golib/cli"
+)
+
+func TestValidateFlags(t *testing.T) {
+	var (
+		dbURL    string
+		mode     string
+		port     int
+		timeout  time.Duration
+		tags     []string
+		level    Level
+		name     string
+		replicas int
+	)
+
+	bindings := []FlagBinding{
+		{Name: "db-url", Target: &dbURL, Required: true},
+		{Name: "mode", Target: &mode, Default: "dev", Enum: []any{"dev", "prod"}},
+		{Name: "port", Target: &port, Default: 8080, Min: 1024, Max: 65535},
+		{Name: "timeout", Target: &timeout, Default: time.Second, Max: time.Minute},
+		{Name: "tags", Target: &tags, Pattern: `^[a-z]+$`, Max: 2},
+		{Name: "level", Target: &level, Default: Warn, Enum: []any{Warn, Info}},
+		{Name: "name", Target: &name, Default: "app", Required: true},
+		{
+			Name:    "replicas",
+			Target:  &replicas,
+			Default: 1,
+			Validate: func(val any) error {
+				if val.(int)%2 == 0 {
+					return fmt.Errorf("replicas must be odd")
+				}
+
+				return nil
+			},
+		},
+	}
+
+	tests := []struct {
+		description string
+		env         map[string]string
+		config      string
+		args        []string
+		want        map[string]Origin // The flags that fail validation and the origin of the values.
+		wantErrs    map[string]error
+	}{
+		{
+			description: "Valid values",
+			env:         map[string]string{"GL_DB_URL": "postgres://localhost/db"},
+			config:      "name: config_app",
+			args:        []string{"--mode=prod", "--tags=a,b", "--level=info"},
+		},
+		{
+			description: "Missing required values",
+			want:        map[string]Origin{"db-url": OriginDefault, "name": OriginDefault},
+			wantErrs:    map[string]error{"db-url": ErrRequired, "name": ErrRequired},
+		},
+		{
+			description: "Invalid values from all sources",
+			env:         map[string]string{"GL_MODE": "test", "GL_REPLICAS": "2"},
+			config:      "db-url: postgres://localhost/db\nname: app\nport: 80\ntimeout: 2m",
+			args:        []string{"--tags=a,b1", "--level=fatal"},
+			want: map[string]Origin{
+				"mode":     OriginEnv,
+				"port":     OriginFile,
+				"timeout":  OriginFile,
+				"tags":     OriginFlag,
+				"level":    OriginFlag,
+				"replicas": OriginEnv,
+			},
+			wantErrs: map[string]error{
+				"mode":    ErrNotInEnum,
+				"port":    ErrOutOfRange,
+				"timeout": ErrOutOfRange,
+				"tags":    ErrPatternMismatch,
+				"level":   ErrNotInEnum,
+			},
+		},
+		{
+			description: "Too many items",
+			args:        []string{"--db-url=postgres://localhost/db", "--name=app", "--tags=a,b,c"},
+			want:        map[string]Origin{"tags": OriginFlag},
+			wantErrs:    map[string]error{"tags": ErrOutOfRange},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			for key, val := range test.env {
+				t.Setenv(key, val)
+			}
+
+			v := NewViper("GL")
+			if test.config != "" {
+				v.SetConfigType("yaml")
+				err := v.ReadConfig(bytes.NewBufferString(test.config))
+				require.NoError(t, err)
+			}
+
+			cmd := cobra.Command{
+				Use: "app_test",
+				RunE: func(cmd *cobra.Command, args []string) error {
+					return ValidateFlags(v, cmd.Flags(), bindings)
+				},
+				SilenceErrors: true,
+				SilenceUsage:  true,
+			}
+
+			err := InitFlags(v, cmd.Flags(), bindings)
+			require.NoError(t, err)
+
+			cmd.SetArgs(test.args)
+			err = cmd.Execute()
+			if len(test.want) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+
+			assert.ErrorIs(t, err, ErrInvalidValue)
+
+			var validationErrs ValidationErrors
+			require.True(t, errors.As(err, &validationErrs))
+
+			got := map[string]Origin{}
+			for _, validationErr := range validationErrs {
+				got[validationErr.Name] = validationErr.Origin
+				if wantErr, ok := test.wantErrs[validationErr.Name]; ok {
+					assert.ErrorIs(t, validationErr, wantErr)
+				}
+			}
+
+			assert.Equal(t, test.want, got)
+		})
+	}
+}
+
+func TestValidateFlags_InvalidRule(t *testing.T) {
+	t.Parallel()
+
+	enabled := true
+	bindings := []FlagBinding{
+		{Name: "enabled", Target: &enabled, Min: 1},
+	}
+
+	cmd := cobra.Command{Run: func(cmd *cobra.Command, args []string) {}}
+	v := NewViper("GL")
+	err := InitFlags(v, cmd.Flags(), bindings)
+	require.NoError(t, err)
+
+	err = ValidateFlags(v, cmd.Flags(), bindings)
+	assert.ErrorIs(t, err, ErrInvalidRule)
+}
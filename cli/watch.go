@@ -0,0 +1,256 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+var ErrNoConfigFile = errors.New("no config file to watch")
+
+// ChangeFunc is called with the old and new value of a binding changed by a reload of the config.
+type ChangeFunc func(name string, oldVal, newVal any)
+
+// ConfigWatcher reloads the config file when it changes and updates the targets bound by InitFlags.
+// The targets are updated under a lock, read them with View if they are accessed concurrently.
+type ConfigWatcher struct {
+	v        *viper.Viper
+	flags    *pflag.FlagSet
+	bindings []FlagBinding
+	watcher  *fsnotify.Watcher
+	done     chan struct{}
+	cancel   context.CancelFunc // Stops watching the sources.
+
+	mu        sync.RWMutex // Guards the targets.
+	cbMu      sync.Mutex   // Guards the callbacks.
+	onChange  []ChangeFunc
+	onError   []func(err error)
+	closeOnce sync.Once
+}
+
+// WatchConfig watches the config files read by v, ie. every layer loaded by LoadConfig and the files of
+// the profile applied by ApplyProfile, and the sources added to v by AddSource and, on every change,
+// re-reads the files and resolves the bindings again with the same precedence as InitFlags, so a
+// value set in a flag or an env variable isn't overridden by the file. Call it after the flags are parsed
+// and Close the watcher when done. It returns ErrNoConfigFile if v has neither a config file nor a source.
+func WatchConfig(v *viper.Viper, flags *pflag.FlagSet, bindings []FlagBinding) (*ConfigWatcher, error) {
+	files := ConfigFiles(v)
+	if len(files) == 0 && v.ConfigFileUsed() != "" {
+		files = []string{v.ConfigFileUsed()}
+	}
+
+	entries := sourcesOf(v)
+	if len(files) == 0 && len(entries) == 0 {
+		return nil, ErrNoConfigFile
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	// Watch the directories to pick up renames, atomic saves and Kubernetes ConfigMap symlink swaps.
+	dirs := map[string]bool{}
+	for _, file := range files {
+		dir := filepath.Dir(file)
+		if dirs[dir] {
+			continue
+		}
+
+		dirs[dir] = true
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	w := ConfigWatcher{
+		v:        v,
+		flags:    flags,
+		bindings: bindings,
+		watcher:  watcher,
+		done:     make(chan struct{}),
+		cancel:   cancel,
+	}
+
+	for _, entry := range entries {
+		err := entry.source.Watch(ctx, func() {
+			if err := w.Reload(); err != nil {
+				w.notifyError(err)
+			}
+		})
+		if err != nil {
+			cancel()
+			watcher.Close()
+			return nil, err
+		}
+	}
+
+	go w.run(files)
+
+	return &w, nil
+}
+
+// OnChange registers a callback that is called for every binding whose value is changed by a reload.
+func (w *ConfigWatcher) OnChange(fn ChangeFunc) {
+	w.cbMu.Lock()
+	defer w.cbMu.Unlock()
+
+	w.onChange = append(w.onChange, fn)
+}
+
+// OnError registers a callback that is called when a reload triggered by a file change fails.
+func (w *ConfigWatcher) OnError(fn func(err error)) {
+	w.cbMu.Lock()
+	defer w.cbMu.Unlock()
+
+	w.onError = append(w.onError, fn)
+}
+
+// View calls fn while holding a read lock on the targets.
+func (w *ConfigWatcher) View(fn func()) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	fn()
+}
+
+// Reload re-reads the config file, or all the config files loaded by LoadConfig, updates the targets
+// with the values of the config and the sources and calls the change callbacks.
+func (w *ConfigWatcher) Reload() error {
+	type change struct {
+		name           string
+		oldVal, newVal any
+	}
+
+	var changes []change
+
+	err := func() error {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		if w.v.ConfigFileUsed() != "" {
+			if err := reloadConfig(w.v); err != nil {
+				return err
+			}
+		}
+
+		var errs BindingErrors
+		for _, binding := range w.bindings {
+			newVal, ok, err := resolveValue(w.v, w.flags, &binding)
+			if err != nil {
+				errs = append(errs, &BindingError{Name: binding.Name, Err: err})
+				continue
+			}
+
+			if !ok {
+				continue
+			}
+
+			target := reflect.ValueOf(binding.Target).Elem()
+			oldVal := target.Interface()
+			if reflect.DeepEqual(oldVal, newVal) {
+				continue
+			}
+
+			target.Set(reflect.ValueOf(newVal))
+			changes = append(changes, change{name: binding.Name, oldVal: oldVal, newVal: newVal})
+		}
+
+		if len(errs) > 0 {
+			return errs
+		}
+
+		return nil
+	}()
+
+	w.cbMu.Lock()
+	callbacks := make([]ChangeFunc, len(w.onChange))
+	copy(callbacks, w.onChange)
+	w.cbMu.Unlock()
+
+	for _, c := range changes {
+		for _, fn := range callbacks {
+			fn(c.name, c.oldVal, c.newVal)
+		}
+	}
+
+	return err
+}
+
+// Close stops watching the config file and the sources.
+func (w *ConfigWatcher) Close() error {
+	var err error
+	w.closeOnce.Do(func() {
+		w.cancel()
+		err = w.watcher.Close()
+		<-w.done
+	})
+
+	return err
+}
+
+func (w *ConfigWatcher) run(files []string) {
+	defer close(w.done)
+
+	// The files mapped to the files they link to.
+	realFiles := make(map[string]string, len(files))
+	for _, file := range files {
+		file = filepath.Clean(file)
+		realFiles[file], _ = filepath.EvalSymlinks(file)
+	}
+
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+
+			// Reload if a config file is written or created, or if the file it links to is changed.
+			_, watched := realFiles[filepath.Clean(event.Name)]
+			written := watched && (event.Has(fsnotify.Write) || event.Has(fsnotify.Create))
+
+			relinked := false
+			for file, realFile := range realFiles {
+				if currentFile, _ := filepath.EvalSymlinks(file); currentFile != "" && currentFile != realFile {
+					realFiles[file] = currentFile
+					relinked = true
+				}
+			}
+
+			if !written && !relinked {
+				continue
+			}
+
+			if err := w.Reload(); err != nil {
+				w.notifyError(err)
+			}
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+
+			w.notifyError(err)
+		}
+	}
+}
+
+func (w *ConfigWatcher) notifyError(err error) {
+	w.cbMu.Lock()
+	callbacks := make([]func(err error), len(w.onError))
+	copy(callbacks, w.onError)
+	w.cbMu.Unlock()
+
+	for _, fn := range callbacks {
+		fn(err)
+	}
+}
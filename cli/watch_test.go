@@ -0,0 +1,218 @@
+package cli_test
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/cybersamx/golib/cli"
+)
+
+type change struct {
+	name   string
+	oldVal any
+	newVal any
+}
+
+func newWatchedCommand(t *testing.T, target *targetVars, config string) (*ConfigWatcher, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte(config), 0o600)
+	require.NoError(t, err)
+
+	bindings := []FlagBinding{
+		{Name: "str-reader", Target: &target.strReader, Default: "str_reader_default"},
+		{Name: "str-env", Target: &target.strEnv, Default: "str_env_default"},
+		{Name: "str-flag", Target: &target.strFlag, Default: "str_flag_default"},
+		{Name: "number", Target: &target.number, Default: 123},
+	}
+
+	v := NewViper("GL")
+	v.SetConfigFile(path)
+	err = v.ReadInConfig()
+	require.NoError(t, err)
+
+	var watcher *ConfigWatcher
+	cmd := cobra.Command{
+		Use: "app_test",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var err error
+			watcher, err = WatchConfig(v, cmd.Flags(), bindings)
+			return err
+		},
+	}
+
+	err = InitFlags(v, cmd.Flags(), bindings)
+	require.NoError(t, err)
+
+	cmd.SetArgs([]string{"--str-flag=str_flag"})
+	err = cmd.Execute()
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		watcher.Close()
+	})
+
+	return watcher, path
+}
+
+func TestWatchConfig(t *testing.T) {
+	t.Setenv("GL_STR_ENV", "str_env")
+
+	var target targetVars
+	watcher, path := newWatchedCommand(t, &target, "str-reader: str_reader\nnumber: 234")
+
+	var (
+		mu      sync.Mutex
+		changes []change
+	)
+
+	done := make(chan struct{}, 1)
+	watcher.OnChange(func(name string, oldVal, newVal any) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		changes = append(changes, change{name: name, oldVal: oldVal, newVal: newVal})
+		if len(changes) == 2 {
+			done <- struct{}{}
+		}
+	})
+
+	// Replace the file atomically so the watcher doesn't see a truncated file.
+	config := "str-reader: str_reader_new\nstr-env: str_env_reader\nstr-flag: str_flag_reader\n"
+	tmpPath := filepath.Join(filepath.Dir(path), "config.tmp")
+	err := os.WriteFile(tmpPath, []byte(config), 0o600)
+	require.NoError(t, err)
+	err = os.Rename(tmpPath, path)
+	require.NoError(t, err)
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the config to reload")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	assert.ElementsMatch(t, []change{
+		{name: "str-reader", oldVal: "str_reader", newVal: "str_reader_new"},
+		{name: "number", oldVal: 234, newVal: 123},
+	}, changes)
+
+	watcher.View(func() {
+		assert.Equal(t, "str_reader_new", target.strReader)
+		assert.Equal(t, "str_env", target.strEnv)
+		assert.Equal(t, "str_flag", target.strFlag)
+		assert.Equal(t, 123, target.number)
+	})
+}
+
+func TestConfigWatcher_Reload(t *testing.T) {
+	var target targetVars
+	watcher, path := newWatchedCommand(t, &target, "number: 234")
+	require.NoError(t, watcher.Close())
+
+	var changes []change
+	watcher.OnChange(func(name string, oldVal, newVal any) {
+		changes = append(changes, change{name: name, oldVal: oldVal, newVal: newVal})
+	})
+
+	err := os.WriteFile(path, []byte("number: 345"), 0o600)
+	require.NoError(t, err)
+
+	err = watcher.Reload()
+	require.NoError(t, err)
+	assert.Equal(t, []change{{name: "number", oldVal: 234, newVal: 345}}, changes)
+	assert.Equal(t, 345, target.number)
+
+	err = os.WriteFile(path, []byte("number: [345"), 0o600)
+	require.NoError(t, err)
+
+	err = watcher.Reload()
+	assert.Error(t, err)
+	assert.Equal(t, 345, target.number)
+}
+
+func TestWatchConfig_NoConfigFile(t *testing.T) {
+	t.Parallel()
+
+	_, err := WatchConfig(NewViper("GL"), nil, nil)
+	assert.ErrorIs(t, err, ErrNoConfigFile)
+}
+
+func TestWatchConfig_Layers(t *testing.T) {
+	systemDir, userDir := t.TempDir(), t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(systemDir, "conf.d"), 0o700))
+
+	// Replace a file atomically so the watcher doesn't see a truncated file.
+	writeFile := func(path, content string) {
+		tmpPath := path + ".tmp"
+		require.NoError(t, os.WriteFile(tmpPath, []byte(content), 0o600))
+		require.NoError(t, os.Rename(tmpPath, path))
+	}
+
+	writeFile(filepath.Join(systemDir, "config.yaml"), "str-reader: system\n")
+	writeFile(filepath.Join(systemDir, "conf.d", "10-number.yaml"), "number: 1\n")
+	writeFile(filepath.Join(userDir, "config.yaml"), "str-env: user\n")
+	writeFile(filepath.Join(userDir, "config.prod.yaml"), "str-flag: prod\n")
+
+	v := NewViper("GL")
+	_, err := LoadConfig(v, ConfigLayers{App: "app", SystemDir: systemDir, UserDir: userDir, WorkDir: t.TempDir()})
+	require.NoError(t, err)
+	require.NoError(t, ApplyProfile(v, "prod"))
+
+	var target targetVars
+	bindings := []FlagBinding{
+		{Name: "str-reader", Target: &target.strReader},
+		{Name: "str-env", Target: &target.strEnv},
+		{Name: "str-flag", Target: &target.strFlag},
+		{Name: "number", Target: &target.number},
+	}
+
+	flags := pflag.NewFlagSet("app", pflag.ContinueOnError)
+	require.NoError(t, InitFlags(v, flags, bindings))
+
+	watcher, err := WatchConfig(v, flags, bindings)
+	require.NoError(t, err)
+	defer watcher.Close()
+
+	changes := make(chan change, 10)
+	watcher.OnChange(func(name string, oldVal, newVal any) {
+		changes <- change{name: name, oldVal: oldVal, newVal: newVal}
+	})
+
+	// A change in any layer, not only in the file read last, is reloaded.
+	tests := []struct {
+		path    string
+		content string
+		want    change
+	}{
+		{path: filepath.Join(systemDir, "config.yaml"), content: "str-reader: system_new\n", want: change{"str-reader", "system", "system_new"}},
+		{path: filepath.Join(systemDir, "conf.d", "10-number.yaml"), content: "number: 2\n", want: change{"number", 1, 2}},
+		{path: filepath.Join(userDir, "config.yaml"), content: "str-env: user_new\n", want: change{"str-env", "user", "user_new"}},
+	}
+
+	for _, tt := range tests {
+		writeFile(tt.path, tt.content)
+
+		select {
+		case got := <-changes:
+			assert.Equal(t, tt.want, got)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for the change of %s", tt.path)
+		}
+	}
+
+	watcher.View(func() {
+		assert.Equal(t, "prod", target.strFlag)
+	})
+}
@@ -0,0 +1,396 @@
+package httputils
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cybersamx/golib/timeutils"
+)
+
+// Directives of the Cache-Control header.
+const (
+	CachePublic               = "public"
+	CachePrivate              = "private"
+	CacheNoCache              = "no-cache"
+	CacheNoStore              = "no-store"
+	CacheNoTransform          = "no-transform"
+	CacheMaxAge               = "max-age"
+	CacheSMaxAge              = "s-maxage"
+	CacheMustRevalidate       = "must-revalidate"
+	CacheProxyRevalidate      = "proxy-revalidate"
+	CacheImmutable            = "immutable"
+	CacheStaleWhileRevalidate = "stale-while-revalidate"
+	CacheStaleIfError         = "stale-if-error"
+)
+
+type cacheDirective struct {
+	name     string
+	value    string
+	hasValue bool
+}
+
+// CacheControl is the value of a Cache-Control header, built fluently or parsed from a header.
+//
+//	cc := httputils.NewCacheControl().Public().MaxAge(time.Hour).StaleWhileRevalidate(time.Minute)
+//	httputils.WriteCacheControl(w, cc) // Cache-Control: public, max-age=3600, stale-while-revalidate=60
+type CacheControl struct {
+	directives []cacheDirective
+}
+
+// NewCacheControl returns an empty CacheControl.
+func NewCacheControl() *CacheControl {
+	return &CacheControl{}
+}
+
+// ParseCacheControl parses the Cache-Control header of a request or a response. The directive names
+// are case-insensitive and a quoted value is unquoted eg. private="Set-Cookie". Invalid directives are
+// kept as is, and ignored by Seconds.
+func ParseCacheControl(header http.Header) *CacheControl {
+	cc := NewCacheControl()
+
+	for _, line := range header.Values(HeaderCacheControl) {
+		for _, field := range splitQuoted(line, ',') {
+			name, value, hasValue := strings.Cut(strings.TrimSpace(field), "=")
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name == "" {
+				continue
+			}
+
+			value = unquoteString(strings.TrimSpace(value))
+
+			cc.set(name, value, hasValue)
+		}
+	}
+
+	return cc
+}
+
+// Public allows shared caches to store the response. It removes the directive private.
+func (cc *CacheControl) Public() *CacheControl {
+	cc.Delete(CachePrivate)
+	return cc.Set(CachePublic)
+}
+
+// Private prevents shared caches from storing the response. It removes the directive public.
+func (cc *CacheControl) Private() *CacheControl {
+	cc.Delete(CachePublic)
+	return cc.Set(CachePrivate)
+}
+
+// NoCache requires caches to revalidate the response before using it.
+func (cc *CacheControl) NoCache() *CacheControl {
+	return cc.Set(CacheNoCache)
+}
+
+// NoStore prevents caches from storing the response.
+func (cc *CacheControl) NoStore() *CacheControl {
+	return cc.Set(CacheNoStore)
+}
+
+// MaxAge sets how long the response is fresh, in seconds.
+func (cc *CacheControl) MaxAge(age time.Duration) *CacheControl {
+	return cc.SetSeconds(CacheMaxAge, age)
+}
+
+// SMaxAge sets how long the response is fresh in shared caches, in seconds.
+func (cc *CacheControl) SMaxAge(age time.Duration) *CacheControl {
+	return cc.SetSeconds(CacheSMaxAge, age)
+}
+
+// MustRevalidate prevents caches from using the response once stale without revalidating it.
+func (cc *CacheControl) MustRevalidate() *CacheControl {
+	return cc.Set(CacheMustRevalidate)
+}
+
+// Immutable tells caches the response won't change while it's fresh.
+func (cc *CacheControl) Immutable() *CacheControl {
+	return cc.Set(CacheImmutable)
+}
+
+// StaleWhileRevalidate allows caches to use the response while it's revalidated in the background, for
+// a duration after it's stale.
+func (cc *CacheControl) StaleWhileRevalidate(stale time.Duration) *CacheControl {
+	return cc.SetSeconds(CacheStaleWhileRevalidate, stale)
+}
+
+// Set sets a directive without value eg. no-transform.
+func (cc *CacheControl) Set(directive string) *CacheControl {
+	cc.set(directive, "", false)
+	return cc
+}
+
+// SetSeconds sets a directive with a duration truncated to seconds eg. stale-if-error=60. A negative
+// duration is set to 0.
+func (cc *CacheControl) SetSeconds(directive string, d time.Duration) *CacheControl {
+	if d < 0 {
+		d = 0
+	}
+
+	cc.set(directive, strconv.Itoa(timeutils.ToSeconds(d)), true)
+
+	return cc
+}
+
+// Delete removes a directive.
+func (cc *CacheControl) Delete(directive string) *CacheControl {
+	directive = strings.ToLower(directive)
+
+	for i, d := range cc.directives {
+		if d.name == directive {
+			cc.directives = append(cc.directives[:i], cc.directives[i+1:]...)
+			break
+		}
+	}
+
+	return cc
+}
+
+// Has returns true if a directive is set.
+func (cc *CacheControl) Has(directive string) bool {
+	_, ok := cc.Get(directive)
+	return ok
+}
+
+// Get returns the value of a directive, empty if the directive has no value, and false if the directive
+// isn't set.
+func (cc *CacheControl) Get(directive string) (string, bool) {
+	directive = strings.ToLower(directive)
+
+	for _, d := range cc.directives {
+		if d.name == directive {
+			return d.value, true
+		}
+	}
+
+	return "", false
+}
+
+// Seconds returns the duration of a directive eg. max-age, and false if the directive isn't set or
+// its value isn't a number of seconds.
+func (cc *CacheControl) Seconds(directive string) (time.Duration, bool) {
+	value, ok := cc.Get(directive)
+	if !ok {
+		return 0, false
+	}
+
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+
+	return time.Duration(seconds) * time.Second, true
+}
+
+// String returns the value of the Cache-Control header, with the directives in the order they are set.
+func (cc *CacheControl) String() string {
+	fields := make([]string, 0, len(cc.directives))
+
+	for _, d := range cc.directives {
+		switch {
+		case !d.hasValue:
+			fields = append(fields, d.name)
+		case !isToken(d.value):
+			fields = append(fields, d.name+"="+quoteString(d.value))
+		default:
+			fields = append(fields, d.name+"="+d.value)
+		}
+	}
+
+	return strings.Join(fields, ", ")
+}
+
+// set sets a directive, keeping its position if it's already set.
+func (cc *CacheControl) set(name, value string, hasValue bool) {
+	name = strings.ToLower(name)
+
+	for i, d := range cc.directives {
+		if d.name == name {
+			cc.directives[i] = cacheDirective{name: name, value: value, hasValue: hasValue}
+			return
+		}
+	}
+
+	cc.directives = append(cc.directives, cacheDirective{name: name, value: value, hasValue: hasValue})
+}
+
+// isToken returns true if a string is an HTTP token, which doesn't need to be quoted.
+func isToken(str string) bool {
+	if str == "" {
+		return false
+	}
+
+	for i := 0; i < len(str); i++ {
+		c := str[i]
+		isAlnum := (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+		if !isAlnum && strings.IndexByte("!#$%&'*+-.^_`|~", c) < 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// quoteString returns an HTTP quoted-string of a string, with only a double quote and a backslash escaped.
+func quoteString(str string) string {
+	var sb strings.Builder
+
+	sb.WriteByte('"')
+	for i := 0; i < len(str); i++ {
+		if c := str[i]; c == '"' || c == '\\' {
+			sb.WriteByte('\\')
+		}
+
+		sb.WriteByte(str[i])
+	}
+	sb.WriteByte('"')
+
+	return sb.String()
+}
+
+// unquoteString returns the content of an HTTP quoted-string, or the string as is if it isn't quoted.
+// A backslash escapes the character after it.
+func unquoteString(str string) string {
+	if len(str) < 2 || str[0] != '"' || str[len(str)-1] != '"' {
+		return str
+	}
+
+	var sb strings.Builder
+
+	str = str[1 : len(str)-1]
+	for i := 0; i < len(str); i++ {
+		if str[i] == '\\' && i+1 < len(str) {
+			i++
+		}
+
+		sb.WriteByte(str[i])
+	}
+
+	return sb.String()
+}
+
+// splitQuoted splits a string by a separator outside of double quotes.
+func splitQuoted(str string, sep byte) []string {
+	var (
+		fields []string
+		quoted bool
+		start  int
+	)
+
+	for i := 0; i < len(str); i++ {
+		switch c := str[i]; {
+		case c == '\\' && quoted:
+			i++
+		case c == '"':
+			quoted = !quoted
+		case c == sep && !quoted:
+			fields = append(fields, str[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(fields, str[start:])
+}
+
+// WriteCacheControl sets the Cache-Control header of a response.
+func WriteCacheControl(w http.ResponseWriter, cc *CacheControl) {
+	w.Header().Set(HeaderCacheControl, cc.String())
+}
+
+// WriteETag sets the ETag header of a response to a quoted tag eg. "v1", or W/"v1" if the tag is weak.
+// The characters not allowed in an entity tag by RFC 9110, ie. a double quote, a backslash, a space or
+// a control character, are removed from the tag.
+func WriteETag(w http.ResponseWriter, tag string, weak bool) {
+	etag := `"` + strings.Map(etagChar, tag) + `"`
+	if weak {
+		etag = "W/" + etag
+	}
+
+	w.Header().Set(HeaderETag, etag)
+}
+
+// etagChar returns r if it's allowed in an entity tag, or -1 to remove it.
+func etagChar(r rune) rune {
+	if r == '!' || (r >= '#' && r <= '~' && r != '\\') || r >= 0x80 {
+		return r
+	}
+
+	return -1
+}
+
+// WriteLastModified sets the Last-Modified header of a response, in UTC truncated to seconds.
+func WriteLastModified(w http.ResponseWriter, modTime time.Time) {
+	if modTime.IsZero() {
+		return
+	}
+
+	w.Header().Set(HeaderLastModified, modTime.UTC().Format(http.TimeFormat))
+}
+
+// IsNotModified returns true if a GET or HEAD request is conditional on a representation other than the
+// one with the ETag and Last-Modified headers of a response, which are set by WriteETag and
+// WriteLastModified. If-None-Match is compared with a weak comparison and takes precedence over
+// If-Modified-Since, as in RFC 9110.
+func IsNotModified(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if match := r.Header.Values(HeaderIfNoneMatch); len(match) > 0 {
+		etag := w.Header().Get(HeaderETag)
+		return etag != "" && matchETag(strings.Join(match, ","), etag)
+	}
+
+	since, err := http.ParseTime(r.Header.Get(HeaderIfModifiedSince))
+	if err != nil {
+		return false
+	}
+
+	modTime, err := http.ParseTime(w.Header().Get(HeaderLastModified))
+	if err != nil {
+		return false
+	}
+
+	return !modTime.After(since)
+}
+
+// WriteNotModified answers a conditional request with 304 Not Modified if IsNotModified and returns
+// true, in which case the handler must not write a body.
+//
+//	httputils.WriteETag(w, version, false)
+//	if httputils.WriteNotModified(w, r) {
+//		return
+//	}
+func WriteNotModified(w http.ResponseWriter, r *http.Request) bool {
+	if !IsNotModified(w, r) {
+		return false
+	}
+
+	// A 304 has no content, like http.ServeContent.
+	h := w.Header()
+	h.Del(HeaderContentType)
+	h.Del(HeaderContentLength)
+	h.Del(HeaderContentEncoding)
+	if h.Get(HeaderETag) != "" {
+		h.Del(HeaderLastModified)
+	}
+
+	w.WriteHeader(http.StatusNotModified)
+
+	return true
+}
+
+// matchETag returns true if an If-None-Match header matches an ETag, ignoring the weak prefixes.
+func matchETag(match, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+
+	for _, tag := range splitQuoted(match, ',') {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,193 @@
+package httputils_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	. "github.com/cybersamx/golib/httputils"
+)
+
+func TestCacheControl_String(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		cc   *CacheControl
+		want string
+	}{
+		{cc: NewCacheControl(), want: ""},
+		{
+			cc:   NewCacheControl().Public().MaxAge(time.Hour).StaleWhileRevalidate(90 * time.Second).Immutable(),
+			want: "public, max-age=3600, stale-while-revalidate=90, immutable",
+		},
+		{
+			cc:   NewCacheControl().Public().Private().SMaxAge(time.Minute).MustRevalidate(),
+			want: "private, s-maxage=60, must-revalidate",
+		},
+		{cc: NewCacheControl().NoStore().NoCache().MaxAge(-time.Second), want: "no-store, no-cache, max-age=0"},
+		{cc: NewCacheControl().MaxAge(time.Hour).MaxAge(1500 * time.Millisecond), want: "max-age=1"},
+		{cc: NewCacheControl().Set(CacheNoTransform).SetSeconds(CacheStaleIfError, time.Minute), want: "no-transform, stale-if-error=60"},
+		{cc: NewCacheControl().NoCache().Private().Delete(CacheNoCache), want: "private"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.cc.String())
+	}
+}
+
+func TestParseCacheControl(t *testing.T) {
+	t.Parallel()
+
+	header := http.Header{}
+	header.Add(HeaderCacheControl, `Public, MAX-AGE=600, private="Set-Cookie, Authorization"`)
+	header.Add(HeaderCacheControl, "stale-while-revalidate=abc, , no-transform")
+
+	cc := ParseCacheControl(header)
+
+	assert.True(t, cc.Has(CachePublic))
+	assert.True(t, cc.Has(CacheNoTransform))
+	assert.False(t, cc.Has(CacheNoStore))
+
+	age, ok := cc.Seconds(CacheMaxAge)
+	assert.True(t, ok)
+	assert.Equal(t, 10*time.Minute, age)
+
+	private, ok := cc.Get(CachePrivate)
+	assert.True(t, ok)
+	assert.Equal(t, "Set-Cookie, Authorization", private)
+
+	_, ok = cc.Seconds(CacheStaleWhileRevalidate)
+	assert.False(t, ok)
+
+	_, ok = cc.Seconds(CacheSMaxAge)
+	assert.False(t, ok)
+
+	want := `public, max-age=600, private="Set-Cookie, Authorization", stale-while-revalidate=abc, no-transform`
+	assert.Equal(t, want, cc.String())
+
+	assert.Equal(t, "", ParseCacheControl(http.Header{}).String())
+
+	// A quoted-string only escapes a double quote and a backslash, unlike a Go string.
+	header = http.Header{HeaderCacheControl: {`ext="a\"b\\c \a é", empty=""`}}
+	cc = ParseCacheControl(header)
+
+	ext, ok := cc.Get("ext")
+	assert.True(t, ok)
+	assert.Equal(t, `a"b\c a é`, ext)
+	assert.Equal(t, `ext="a\"b\\c a é", empty=""`, cc.String())
+}
+
+func TestWriteNotModified(t *testing.T) {
+	t.Parallel()
+
+	modTime := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		method     string
+		header     map[string]string
+		etag       string
+		weak       bool
+		modTime    time.Time
+		wantStatus int
+	}{
+		{name: "Unconditional", method: http.MethodGet, etag: "v1", modTime: modTime, wantStatus: http.StatusOK},
+		{
+			name: "Matching etag", method: http.MethodGet, etag: "v1", modTime: modTime,
+			header:     map[string]string{HeaderIfNoneMatch: `"v0", W/"v1"`},
+			wantStatus: http.StatusNotModified,
+		},
+		{
+			name: "Weak etag", method: http.MethodHead, etag: "v1", weak: true,
+			header:     map[string]string{HeaderIfNoneMatch: `"v1"`},
+			wantStatus: http.StatusNotModified,
+		},
+		{
+			name: "Any etag", method: http.MethodGet, etag: "v1",
+			header:     map[string]string{HeaderIfNoneMatch: "*"},
+			wantStatus: http.StatusNotModified,
+		},
+		{
+			name: "Other etag", method: http.MethodGet, etag: "v2", modTime: modTime,
+			header: map[string]string{
+				HeaderIfNoneMatch:     `"v1"`,
+				HeaderIfModifiedSince: modTime.Format(http.TimeFormat),
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "Unquoted characters", method: http.MethodGet, etag: `v"1\ é`,
+			header:     map[string]string{HeaderIfNoneMatch: `"v1é"`},
+			wantStatus: http.StatusNotModified,
+		},
+		{
+			name: "Not modified since", method: http.MethodGet, modTime: modTime.Add(500 * time.Millisecond),
+			header:     map[string]string{HeaderIfModifiedSince: modTime.Format(http.TimeFormat)},
+			wantStatus: http.StatusNotModified,
+		},
+		{
+			name: "Modified since", method: http.MethodGet, modTime: modTime.Add(time.Second),
+			header:     map[string]string{HeaderIfModifiedSince: modTime.Format(http.TimeFormat)},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "Invalid date", method: http.MethodGet, modTime: modTime,
+			header:     map[string]string{HeaderIfModifiedSince: "yesterday"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "Post", method: http.MethodPost, etag: "v1",
+			header:     map[string]string{HeaderIfNoneMatch: `"v1"`},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(tt.method, "/", nil)
+			for key, val := range tt.header {
+				r.Header.Set(key, val)
+			}
+
+			w := httptest.NewRecorder()
+			w.Header().Set(HeaderContentType, "text/plain")
+			if tt.etag != "" {
+				WriteETag(w, tt.etag, tt.weak)
+			}
+			WriteLastModified(w, tt.modTime)
+
+			if !WriteNotModified(w, r) {
+				w.WriteHeader(http.StatusOK)
+			}
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusNotModified {
+				assert.Empty(t, w.Header().Get(HeaderContentType))
+			}
+		})
+	}
+}
+
+func TestWriteETag(t *testing.T) {
+	t.Parallel()
+
+	w := httptest.NewRecorder()
+	WriteETag(w, "abc", false)
+	assert.Equal(t, `"abc"`, w.Header().Get(HeaderETag))
+
+	WriteETag(w, "abc", true)
+	assert.Equal(t, `W/"abc"`, w.Header().Get(HeaderETag))
+
+	// The characters not allowed in an entity tag are removed.
+	WriteETag(w, "a\"b\\c d\té!", false)
+	assert.Equal(t, `"abcdé!"`, w.Header().Get(HeaderETag))
+
+	WriteLastModified(w, time.Date(2023, 5, 1, 12, 0, 0, 0, time.FixedZone("PDT", -7*3600)))
+	assert.Equal(t, "Mon, 01 May 2023 19:00:00 GMT", w.Header().Get(HeaderLastModified))
+}
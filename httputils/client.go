@@ -0,0 +1,294 @@
+package httputils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cybersamx/golib/ioutils"
+)
+
+const (
+	defaultMaxRetries = 3
+	defaultMinBackoff = 100 * time.Millisecond
+	defaultMaxBackoff = 10 * time.Second
+
+	// Most bytes of the body of a failed response read to reuse the connection.
+	maxDrainBytes = 4096
+)
+
+// Client wraps an *http.Client to retry the idempotent requests that fail with a network error, 429
+// Too Many Requests or a 5xx status, except 501 Not Implemented.
+type Client struct {
+	httpClient *http.Client
+	maxRetries int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	onAttempt  []func(attempt *Attempt)
+}
+
+// Attempt is an attempt of a request by a Client, passed to the hooks registered with WithAttemptHook.
+type Attempt struct {
+	Request  *http.Request
+	Number   int            // 1 for the first attempt.
+	Response *http.Response // Nil if Err is set.
+	Err      error
+	Duration time.Duration // Duration of the attempt.
+	Retry    bool          // True if the request is retried after Wait.
+	Wait     time.Duration
+}
+
+// ClientOption sets an option of a Client.
+type ClientOption func(c *Client)
+
+// NewClient returns a Client retrying a request 3 times with a backoff between 100ms and 10s.
+func NewClient(opts ...ClientOption) *Client {
+	c := Client{
+		httpClient: http.DefaultClient,
+		maxRetries: defaultMaxRetries,
+		minBackoff: defaultMinBackoff,
+		maxBackoff: defaultMaxBackoff,
+	}
+
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return &c
+}
+
+// WithHTTPClient sets the client sending the requests, http.DefaultClient by default.
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(c *Client) {
+		c.httpClient = client
+	}
+}
+
+// WithMaxRetries sets how many times a request is retried, 0 to never retry.
+func WithMaxRetries(retries int) ClientOption {
+	return func(c *Client) {
+		c.maxRetries = retries
+	}
+}
+
+// WithBackoff sets the wait before the first retry, doubled before every next retry up to maxBackoff. A
+// response with a Retry-After header longer than maxBackoff isn't retried.
+func WithBackoff(minBackoff, maxBackoff time.Duration) ClientOption {
+	return func(c *Client) {
+		c.minBackoff = minBackoff
+		c.maxBackoff = maxBackoff
+	}
+}
+
+// WithAttemptHook adds a hook called after every attempt of a request eg. to record metrics.
+func WithAttemptHook(fn func(attempt *Attempt)) ClientOption {
+	return func(c *Client) {
+		c.onAttempt = append(c.onAttempt, fn)
+	}
+}
+
+// Do sends a request like http.Client.Do, retrying it if it's idempotent, ie. its method is GET, HEAD,
+// OPTIONS, TRACE, PUT or DELETE or it has an Idempotency-Key header. The wait before a retry is the
+// backoff with a random jitter, or the Retry-After of the response. The body of the request is buffered
+// to be sent again, unless the request has a GetBody.
+//
+// A request isn't retried once its context is done, or if the wait would exceed the deadline of its
+// context, in which case the last response or error is returned.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	retries := c.maxRetries
+	if !isIdempotent(req) {
+		retries = 0
+	}
+
+	if retries > 0 && req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		if err := bufferBody(req); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx := req.Context()
+
+	for n := 1; ; n++ {
+		attemptReq := req
+		if n > 1 {
+			var err error
+			if attemptReq, err = rewindRequest(req); err != nil {
+				return nil, err
+			}
+		}
+
+		start := time.Now()
+		resp, err := c.httpClient.Do(attemptReq)
+
+		attempt := Attempt{
+			Request:  attemptReq,
+			Number:   n,
+			Response: resp,
+			Err:      err,
+			Duration: time.Since(start),
+		}
+
+		if n <= retries && ctx.Err() == nil && shouldRetry(resp, err) {
+			attempt.Wait, attempt.Retry = c.wait(ctx, n, resp)
+		}
+
+		for _, fn := range c.onAttempt {
+			fn(&attempt)
+		}
+
+		if !attempt.Retry {
+			if err != nil && n > 1 {
+				err = fmt.Errorf("request failed after %d attempts: %w", n, err)
+			}
+
+			return resp, err
+		}
+
+		if resp != nil {
+			drainBody(resp)
+		}
+
+		timer := time.NewTimer(attempt.Wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// wait returns the wait before retrying the nth attempt, and false if the request can't be retried in
+// time.
+func (c *Client) wait(ctx context.Context, n int, resp *http.Response) (time.Duration, bool) {
+	wait, ok := retryAfter(resp)
+	if ok && wait > c.maxBackoff {
+		return 0, false
+	}
+
+	if !ok {
+		wait = c.backoff(n)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && time.Now().Add(wait).After(deadline) {
+		return 0, false
+	}
+
+	return wait, true
+}
+
+// backoff returns the exponential backoff of the nth attempt with a random jitter, between half and
+// all of the backoff.
+func (c *Client) backoff(n int) time.Duration {
+	backoff := c.maxBackoff
+	if n < 32 && c.minBackoff<<(n-1) < c.maxBackoff {
+		backoff = c.minBackoff << (n - 1)
+	}
+
+	if backoff <= 0 {
+		return 0
+	}
+
+	half := backoff / 2
+
+	return half + time.Duration(rand.Int63n(int64(backoff-half)+1))
+}
+
+// retryAfter returns the wait of the Retry-After header of a response, in seconds or as a date.
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil {
+		return 0, false
+	}
+
+	value := resp.Header.Get(HeaderRetryAfter)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+
+	if wait := time.Until(date); wait > 0 {
+		return wait, true
+	}
+
+	return 0, true
+}
+
+func shouldRetry(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+
+	return resp.StatusCode == http.StatusTooManyRequests ||
+		(resp.StatusCode >= http.StatusInternalServerError && resp.StatusCode != http.StatusNotImplemented)
+}
+
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	return req.Header.Get(HeaderIdempotencyKey) != ""
+}
+
+// bufferBody reads the body of a request in memory and sets its GetBody to send it again.
+func bufferBody(req *http.Request) error {
+	_, clone, err := ioutils.CloneReader(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return fmt.Errorf("failed to buffer the request body: %w", err)
+	}
+
+	// The clone is a *bytes.Buffer, whose content is reused instead of copied again.
+	var content []byte
+	if buf, ok := clone.(interface{ Bytes() []byte }); ok {
+		content = buf.Bytes()
+	} else if content, err = io.ReadAll(clone); err != nil {
+		return fmt.Errorf("failed to buffer the request body: %w", err)
+	}
+
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(content)), nil
+	}
+	req.Body, _ = req.GetBody()
+	req.ContentLength = int64(len(content))
+
+	return nil
+}
+
+// rewindRequest returns a copy of a request with its body rewound.
+func rewindRequest(req *http.Request) (*http.Request, error) {
+	clone := req.Clone(req.Context())
+	if req.GetBody == nil {
+		return clone, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("failed to rewind the request body: %w", err)
+	}
+
+	clone.Body = body
+
+	return clone, nil
+}
+
+// drainBody reads a bit of the body of a response and closes it, so the connection can be reused.
+func drainBody(resp *http.Response) {
+	_, _ = io.CopyN(io.Discard, resp.Body, maxDrainBytes)
+	resp.Body.Close()
+}
@@ -0,0 +1,218 @@
+package httputils_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/cybersamx/golib/httputils"
+)
+
+// statusServer responds with the statuses in order, then with 200, and records the request bodies.
+func statusServer(t *testing.T, header http.Header, statuses ...int) (*httptest.Server, *[]string) {
+	t.Helper()
+
+	var (
+		mu     sync.Mutex
+		bodies []string
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+
+		status := http.StatusOK
+		if len(bodies) <= len(statuses) {
+			status = statuses[len(bodies)-1]
+			for key, values := range header {
+				w.Header()[key] = values
+			}
+		}
+
+		w.WriteHeader(status)
+		io.WriteString(w, http.StatusText(status))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &bodies
+}
+
+func TestClient_Do(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		method     string
+		header     http.Header
+		reqHeader  http.Header
+		statuses   []int
+		wantStatus int
+		wantCalls  int
+	}{
+		{name: "Success", method: http.MethodGet, wantStatus: http.StatusOK, wantCalls: 1},
+		{
+			name: "Retried 5xx", method: http.MethodPut,
+			statuses:   []int{http.StatusServiceUnavailable, http.StatusBadGateway},
+			wantStatus: http.StatusOK, wantCalls: 3,
+		},
+		{
+			name: "Retry-After", method: http.MethodGet, header: http.Header{HeaderRetryAfter: {"0"}},
+			statuses:   []int{http.StatusTooManyRequests},
+			wantStatus: http.StatusOK, wantCalls: 2,
+		},
+		{
+			name: "Retry-After too long", method: http.MethodGet, header: http.Header{HeaderRetryAfter: {"120"}},
+			statuses:   []int{http.StatusTooManyRequests},
+			wantStatus: http.StatusTooManyRequests, wantCalls: 1,
+		},
+		{
+			name: "Max retries", method: http.MethodDelete,
+			statuses:   []int{500, 500, 500, 500, 500},
+			wantStatus: http.StatusInternalServerError, wantCalls: 4,
+		},
+		{
+			name: "Not retried status", method: http.MethodGet,
+			statuses:   []int{http.StatusNotImplemented},
+			wantStatus: http.StatusNotImplemented, wantCalls: 1,
+		},
+		{
+			name: "Not idempotent", method: http.MethodPost,
+			statuses:   []int{http.StatusServiceUnavailable},
+			wantStatus: http.StatusServiceUnavailable, wantCalls: 1,
+		},
+		{
+			name: "Idempotency key", method: http.MethodPost, reqHeader: http.Header{HeaderIdempotencyKey: {"key-1"}},
+			statuses:   []int{http.StatusServiceUnavailable},
+			wantStatus: http.StatusOK, wantCalls: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			server, bodies := statusServer(t, tt.header, tt.statuses...)
+
+			var attempts []Attempt
+			client := NewClient(
+				WithHTTPClient(server.Client()),
+				WithBackoff(time.Millisecond, time.Minute),
+				WithAttemptHook(func(attempt *Attempt) {
+					attempts = append(attempts, *attempt)
+				}),
+			)
+
+			req, err := http.NewRequest(tt.method, server.URL, io.NopCloser(strings.NewReader("payload")))
+			require.NoError(t, err)
+			for key, values := range tt.reqHeader {
+				req.Header[key] = values
+			}
+
+			resp, err := client.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			assert.Equal(t, http.StatusText(tt.wantStatus), string(body))
+
+			// The body is sent again on every attempt.
+			assert.Len(t, *bodies, tt.wantCalls)
+			for _, body := range *bodies {
+				assert.Equal(t, "payload", body)
+			}
+
+			require.Len(t, attempts, tt.wantCalls)
+			for i, attempt := range attempts {
+				assert.Equal(t, i+1, attempt.Number)
+				assert.Equal(t, i < tt.wantCalls-1, attempt.Retry)
+				assert.NotNil(t, attempt.Response)
+				assert.Positive(t, attempt.Duration)
+			}
+		})
+	}
+}
+
+func TestClient_Do_NetworkError(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.NotFoundHandler())
+	url := server.URL
+	server.Close()
+
+	attempts := 0
+	client := NewClient(
+		WithMaxRetries(2),
+		WithBackoff(time.Millisecond, 10*time.Millisecond),
+		WithAttemptHook(func(attempt *Attempt) {
+			attempts++
+			assert.Error(t, attempt.Err)
+			assert.Nil(t, attempt.Response)
+		}),
+	)
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+
+	_, err = client.Do(req)
+	assert.ErrorContains(t, err, "request failed after 3 attempts: ")
+	assert.Equal(t, 3, attempts)
+}
+
+func TestClient_Do_Deadline(t *testing.T) {
+	t.Parallel()
+
+	server, bodies := statusServer(t, nil, http.StatusServiceUnavailable)
+	client := NewClient(WithHTTPClient(server.Client()), WithBackoff(time.Second, time.Second))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+
+	// The backoff exceeds the deadline, so the response is returned without retrying.
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Len(t, *bodies, 1)
+}
+
+func TestClient_Do_Canceled(t *testing.T) {
+	t.Parallel()
+
+	server, _ := statusServer(t, nil, http.StatusServiceUnavailable)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client := NewClient(
+		WithHTTPClient(server.Client()),
+		WithBackoff(time.Minute, time.Minute),
+		WithAttemptHook(func(attempt *Attempt) {
+			if attempt.Retry {
+				cancel()
+			}
+		}),
+	)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+
+	_, err = client.Do(req)
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -7,9 +7,23 @@ import (
 
 const (
 	HeaderXForwardedProto = "X-Forwarded-Proto"
+	HeaderXForwardedFor   = "X-Forwarded-For"
+	HeaderXForwardedHost  = "X-Forwarded-Host"
+	HeaderForwarded       = "Forwarded"
 	HeaderExpires         = "Expires"
 	HeaderCacheControl    = "Cache-Control"
 	HeaderPragma          = "Pragma"
+	HeaderETag            = "ETag"
+	HeaderLastModified    = "Last-Modified"
+	HeaderIfNoneMatch     = "If-None-Match"
+	HeaderIfModifiedSince = "If-Modified-Since"
+	HeaderContentType     = "Content-Type"
+	HeaderContentLength   = "Content-Length"
+	HeaderContentEncoding = "Content-Encoding"
+	HeaderAccept          = "Accept"
+	HeaderXRequestID      = "X-Request-ID"
+	HeaderRetryAfter      = "Retry-After"
+	HeaderIdempotencyKey  = "Idempotency-Key"
 )
 
 func IsStatusCode2xx(code int) bool {
@@ -18,6 +32,6 @@ func IsStatusCode2xx(code int) bool {
 
 func WriteNoCacheHeaders(w http.ResponseWriter) {
 	w.Header().Set(HeaderExpires, time.Unix(0, 0).Format(time.RFC1123))
-	w.Header().Set(HeaderCacheControl, "no-cache, private, max-age=0")
+	WriteCacheControl(w, NewCacheControl().NoCache().Private().MaxAge(0))
 	w.Header().Set(HeaderPragma, "no-cache")
 }
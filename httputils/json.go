@@ -0,0 +1,287 @@
+package httputils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/cybersamx/golib/serialization"
+)
+
+const (
+	MIMEApplicationJSON = "application/json"
+
+	// Default limit of the size of the body of a request decoded by DecodeJSONRequest.
+	defaultMaxJSONBytes = 1 << 20
+
+	// Most bytes of the body of an error response read by DoJSON.
+	maxErrorBodyBytes = 1 << 20
+)
+
+var (
+	ErrUnsupportedMediaType = errors.New("content type must be application/json")
+	ErrBodyTooLarge         = errors.New("request body too large")
+	ErrInvalidJSON          = errors.New("invalid json body")
+	ErrUnexpectedStatus     = errors.New("unexpected status")
+	ErrUnsupportedType      = errors.New("type can't be decoded from json")
+)
+
+// StatusError is an error of a request with the status of the response answering it.
+type StatusError struct {
+	Status int
+	Err    error
+}
+
+func (e *StatusError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeOption sets an option of DecodeJSONRequest.
+type DecodeOption func(opts *decodeOptions)
+
+type decodeOptions struct {
+	maxBytes        int64
+	disallowUnknown bool
+}
+
+// WithMaxBytes limits the size of the body of a request, 1MB by default.
+func WithMaxBytes(n int64) DecodeOption {
+	return func(opts *decodeOptions) {
+		opts.maxBytes = n
+	}
+}
+
+// WithUnknownFieldsDisallowed rejects a json object with a field that isn't in the struct of type T.
+func WithUnknownFieldsDisallowed() DecodeOption {
+	return func(opts *decodeOptions) {
+		opts.disallowUnknown = true
+	}
+}
+
+// DecodeJSONRequest decodes the json body of a request into a value of type T eg. a struct, a slice or
+// any, with serialization.ParseJSON. On failure, it returns a *StatusError with the status to respond
+// with:
+//   - 415 Unsupported Media Type wrapping ErrUnsupportedMediaType if the Content-Type isn't
+//     application/json or a +json type eg. application/merge-patch+json.
+//   - 413 Request Entity Too Large wrapping ErrBodyTooLarge if the body exceeds the size limit.
+//   - 400 Bad Request wrapping ErrInvalidJSON if the body is empty, isn't a single valid json value or
+//     doesn't match T.
+//   - 500 Internal Server Error wrapping ErrUnsupportedType if T isn't supported by
+//     serialization.ParseJSON eg. a string.
+//
+// For example:
+//
+//	input, err := httputils.DecodeJSONRequest[CreateUserInput](r, httputils.WithUnknownFieldsDisallowed())
+//	var statusErr *httputils.StatusError
+//	if errors.As(err, &statusErr) {
+//		http.Error(w, statusErr.Error(), statusErr.Status)
+//		return
+//	}
+func DecodeJSONRequest[T any](r *http.Request, opts ...DecodeOption) (T, error) {
+	var zero T
+
+	if err := checkJSONType[T](); err != nil {
+		return zero, &StatusError{Status: http.StatusInternalServerError, Err: err}
+	}
+
+	options := decodeOptions{maxBytes: defaultMaxJSONBytes}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if !isJSONContentType(r.Header.Get(HeaderContentType)) {
+		return zero, &StatusError{Status: http.StatusUnsupportedMediaType, Err: ErrUnsupportedMediaType}
+	}
+
+	if r.Body == nil {
+		return zero, &StatusError{Status: http.StatusBadRequest, Err: fmt.Errorf("%w: empty body", ErrInvalidJSON)}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, options.maxBytes+1))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return zero, &StatusError{Status: http.StatusRequestEntityTooLarge, Err: ErrBodyTooLarge}
+		}
+
+		err = fmt.Errorf("failed to read the request body: %w", err)
+		return zero, &StatusError{Status: http.StatusBadRequest, Err: err}
+	}
+
+	if int64(len(body)) > options.maxBytes {
+		return zero, &StatusError{Status: http.StatusRequestEntityTooLarge, Err: ErrBodyTooLarge}
+	}
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		return zero, &StatusError{Status: http.StatusBadRequest, Err: fmt.Errorf("%w: empty body", ErrInvalidJSON)}
+	}
+
+	parseOpts := []serialization.ParseOption{serialization.WithDisallowTrailingData()}
+	if options.disallowUnknown {
+		parseOpts = append(parseOpts, serialization.WithDisallowUnknownFields())
+	}
+
+	val, err := serialization.ParseJSON[T](bytes.NewReader(body), parseOpts...)
+	if err != nil {
+		return zero, &StatusError{Status: http.StatusBadRequest, Err: fmt.Errorf("%w: %w", ErrInvalidJSON, err)}
+	}
+
+	return val, nil
+}
+
+// checkJSONType returns ErrUnsupportedType if serialization.ParseJSON can't parse json into a value
+// of type T.
+func checkJSONType[T any]() error {
+	if err := serialization.CheckJSONType[T](); err != nil {
+		return fmt.Errorf("%w: %w", ErrUnsupportedType, err)
+	}
+
+	return nil
+}
+
+// isJSONContentType returns true if a content type is application/json or a +json type.
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return mediaType == MIMEApplicationJSON ||
+		(strings.HasPrefix(mediaType, "application/") && strings.HasSuffix(mediaType, "+json"))
+}
+
+// WriteJSON writes a response with a status and v encoded in json. If v can't be encoded, it responds
+// with 500 Internal Server Error instead and returns the error.
+func WriteJSON(w http.ResponseWriter, status int, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return fmt.Errorf("failed to marshal %T to json: %w", v, err)
+	}
+
+	w.Header().Set(HeaderContentType, MIMEApplicationJSON+"; charset=utf-8")
+	w.WriteHeader(status)
+
+	_, err = w.Write(append(body, '\n'))
+
+	return err
+}
+
+// ResponseError is the error of a response with a status other than 2xx, returned by DoJSON.
+type ResponseError struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte // Up to 1MB of the body.
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("%s %d %s", ErrUnexpectedStatus, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+func (e *ResponseError) Unwrap() error {
+	return ErrUnexpectedStatus
+}
+
+// ErrorPayload decodes the body of the *ResponseError wrapped in err into a value of type E, eg. the
+// error type of an API. It returns false if err doesn't wrap a *ResponseError or its body can't be
+// decoded into E.
+func ErrorPayload[E any](err error) (E, bool) {
+	var respErr *ResponseError
+	if !errors.As(err, &respErr) || len(bytes.TrimSpace(respErr.Body)) == 0 {
+		return *new(E), false
+	}
+
+	payload, err := serialization.ParseJSON[E](bytes.NewReader(respErr.Body))
+	if err != nil {
+		return *new(E), false
+	}
+
+	return payload, true
+}
+
+// DoJSON sends a request with body encoded in json with client, and decodes the json body of a 2xx
+// response into a value of type Resp with serialization.ParseJSON eg. a struct, a slice or any. A nil
+// body, eg. of a GET, isn't sent. A response with an empty body, eg. 204 No Content, is the zero value
+// of Resp. A response with any other status is returned as a *ResponseError, whose payload is decoded
+// with ErrorPayload. If client is nil, a client created by NewClient is used. If Resp isn't supported
+// by serialization.ParseJSON, DoJSON returns ErrUnsupportedType without sending the request.
+//
+//	user, err := httputils.DoJSON[CreateUserInput, User](ctx, client, http.MethodPost, url, input)
+//	if apiErr, ok := httputils.ErrorPayload[APIError](err); ok {
+//		...
+//	}
+func DoJSON[Req, Resp any](ctx context.Context, client *Client, method, url string, body Req) (Resp, error) {
+	var zero Resp
+
+	if err := checkJSONType[Resp](); err != nil {
+		return zero, err
+	}
+
+	if client == nil {
+		client = NewClient()
+	}
+
+	var reader io.Reader
+	if !isNil(body) {
+		content, err := json.Marshal(body)
+		if err != nil {
+			return zero, fmt.Errorf("failed to marshal %T to json: %w", body, err)
+		}
+
+		reader = bytes.NewReader(content)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return zero, err
+	}
+
+	req.Header.Set(HeaderAccept, MIMEApplicationJSON)
+	if reader != nil {
+		req.Header.Set(HeaderContentType, MIMEApplicationJSON)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return zero, err
+	}
+	defer resp.Body.Close()
+
+	if !IsStatusCode2xx(resp.StatusCode) {
+		errBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+		return zero, &ResponseError{StatusCode: resp.StatusCode, Header: resp.Header, Body: errBody}
+	}
+
+	val, err := serialization.ParseJSON[Resp](resp.Body, serialization.WithDisallowTrailingData())
+	if err != nil {
+		return zero, err
+	}
+
+	return val, nil
+}
+
+// isNil returns true if v is nil or a nil pointer, map, slice or interface.
+func isNil(v any) bool {
+	rv := reflect.ValueOf(v)
+	if !rv.IsValid() {
+		return true
+	}
+
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface:
+		return rv.IsNil()
+	}
+
+	return false
+}
@@ -0,0 +1,263 @@
+package httputils_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/cybersamx/golib/httputils"
+)
+
+type user struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func TestDecodeJSONRequest(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        io.Reader
+		opts        []DecodeOption
+		want        user
+		wantStatus  int
+		wantErr     error
+	}{
+		{
+			name: "Valid", contentType: "application/json; charset=utf-8", body: strings.NewReader(`{"name":"mike","age":25}`),
+			want: user{Name: "mike", Age: 25},
+		},
+		{
+			name: "JSON suffix", contentType: "application/merge-patch+json", body: strings.NewReader(`{"age":26}`),
+			want: user{Age: 26},
+		},
+		{
+			name: "Unknown field allowed", contentType: "application/json", body: strings.NewReader(`{"name":"mike","email":"m"}`),
+			want: user{Name: "mike"},
+		},
+		{
+			name: "Unknown field disallowed", contentType: "application/json", body: strings.NewReader(`{"name":"mike","email":"m"}`),
+			opts:       []DecodeOption{WithUnknownFieldsDisallowed()},
+			wantStatus: http.StatusBadRequest, wantErr: ErrInvalidJSON,
+		},
+		{
+			name: "No content type", body: strings.NewReader(`{}`),
+			wantStatus: http.StatusUnsupportedMediaType, wantErr: ErrUnsupportedMediaType,
+		},
+		{
+			name: "Text", contentType: "text/plain", body: strings.NewReader(`{}`),
+			wantStatus: http.StatusUnsupportedMediaType, wantErr: ErrUnsupportedMediaType,
+		},
+		{
+			name: "Too large", contentType: "application/json", body: strings.NewReader(`{"name":"mike"}`),
+			opts:       []DecodeOption{WithMaxBytes(8)},
+			wantStatus: http.StatusRequestEntityTooLarge, wantErr: ErrBodyTooLarge,
+		},
+		{
+			name: "Empty", contentType: "application/json", body: strings.NewReader(" \n"),
+			wantStatus: http.StatusBadRequest, wantErr: ErrInvalidJSON,
+		},
+		{
+			name: "Invalid json", contentType: "application/json", body: strings.NewReader(`{"name":`),
+			wantStatus: http.StatusBadRequest, wantErr: ErrInvalidJSON,
+		},
+		{
+			name: "Trailing data", contentType: "application/json", body: strings.NewReader(`{"name":"mike"} garbage`),
+			wantStatus: http.StatusBadRequest, wantErr: ErrInvalidJSON,
+		},
+		{
+			name: "Multiple values", contentType: "application/json", body: strings.NewReader(`{"name":"mike"} {}`),
+			wantStatus: http.StatusBadRequest, wantErr: ErrInvalidJSON,
+		},
+		{
+			name: "Wrong type", contentType: "application/json", body: strings.NewReader(`{"age":"old"}`),
+			wantStatus: http.StatusBadRequest, wantErr: ErrInvalidJSON,
+		},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/users", tt.body)
+		if tt.contentType != "" {
+			r.Header.Set(HeaderContentType, tt.contentType)
+		}
+
+		got, err := DecodeJSONRequest[user](r, tt.opts...)
+		if tt.wantErr == nil {
+			require.NoError(t, err, tt.name)
+			assert.Equal(t, tt.want, got, tt.name)
+			continue
+		}
+
+		assert.ErrorIs(t, err, tt.wantErr, tt.name)
+
+		var statusErr *StatusError
+		require.ErrorAs(t, err, &statusErr, tt.name)
+		assert.Equal(t, tt.wantStatus, statusErr.Status, tt.name)
+	}
+}
+
+func TestDecodeJSONRequest_Types(t *testing.T) {
+	t.Parallel()
+
+	newRequest := func(body string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body))
+		r.Header.Set(HeaderContentType, MIMEApplicationJSON)
+
+		return r
+	}
+
+	users, err := DecodeJSONRequest[[]user](newRequest(`[{"name":"mike"},{"name":"jane","age":30}]`))
+	require.NoError(t, err)
+	assert.Equal(t, []user{{Name: "mike"}, {Name: "jane", Age: 30}}, users)
+
+	val, err := DecodeJSONRequest[any](newRequest(`[1,"a"]`))
+	require.NoError(t, err)
+	assert.Equal(t, []any{float64(1), "a"}, val)
+
+	_, err = DecodeJSONRequest[[]user](newRequest(`{"name":"mike"}`))
+	assert.ErrorIs(t, err, ErrInvalidJSON)
+
+	// The type parameter is a fault of the server, not of the request.
+	_, err = DecodeJSONRequest[func()](newRequest(`{}`))
+	assert.ErrorIs(t, err, ErrUnsupportedType)
+
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusInternalServerError, statusErr.Status)
+}
+
+func TestDecodeJSONRequest_MaxBodySize(t *testing.T) {
+	t.Parallel()
+
+	var err error
+	handler := MaxBodySize(8)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err = DecodeJSONRequest[user](r)
+	}))
+
+	r := httptest.NewRequest(http.MethodPost, "/users", io.MultiReader(strings.NewReader(`{"name":"mike"}`)))
+	r.Header.Set(HeaderContentType, MIMEApplicationJSON)
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, statusErr.Status)
+}
+
+func TestWriteJSON(t *testing.T) {
+	t.Parallel()
+
+	w := httptest.NewRecorder()
+	err := WriteJSON(w, http.StatusCreated, user{Name: "mike", Age: 25})
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "application/json; charset=utf-8", w.Header().Get(HeaderContentType))
+	assert.Equal(t, "{\"name\":\"mike\",\"age\":25}\n", w.Body.String())
+
+	w = httptest.NewRecorder()
+	err = WriteJSON(w, http.StatusOK, map[string]any{"fn": func() {}})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestDoJSON(t *testing.T) {
+	t.Parallel()
+
+	var posts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users":
+			if r.Method == http.MethodGet {
+				WriteJSON(w, http.StatusOK, []user{{Name: "mike", Age: 25}, {Name: "jane", Age: 30}})
+				return
+			}
+
+			posts.Add(1)
+			input, err := DecodeJSONRequest[user](r, WithUnknownFieldsDisallowed())
+			if err != nil {
+				var statusErr *StatusError
+				errors.As(err, &statusErr)
+				WriteJSON(w, statusErr.Status, apiError{Code: "invalid", Message: err.Error()})
+				return
+			}
+
+			input.Age++
+			WriteJSON(w, http.StatusCreated, input)
+		case "/users/mike":
+			assert.Equal(t, MIMEApplicationJSON, r.Header.Get(HeaderAccept))
+			assert.Empty(t, r.Header.Get(HeaderContentType))
+			WriteJSON(w, http.StatusOK, user{Name: "mike", Age: 25})
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	client := NewClient(WithHTTPClient(server.Client()), WithBackoff(time.Millisecond, time.Millisecond))
+
+	created, err := DoJSON[user, user](ctx, client, http.MethodPost, server.URL+"/users", user{Name: "mike", Age: 25})
+	require.NoError(t, err)
+	assert.Equal(t, user{Name: "mike", Age: 26}, created)
+
+	got, err := DoJSON[*user, *user](ctx, nil, http.MethodGet, server.URL+"/users/mike", nil)
+	require.NoError(t, err)
+	assert.Equal(t, &user{Name: "mike", Age: 25}, got)
+
+	empty, err := DoJSON[any, map[string]any](ctx, client, http.MethodDelete, server.URL+"/empty", nil)
+	require.NoError(t, err)
+	assert.Nil(t, empty)
+
+	list, err := DoJSON[any, []user](ctx, client, http.MethodGet, server.URL+"/users", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []user{{Name: "mike", Age: 25}, {Name: "jane", Age: 30}}, list)
+
+	val, err := DoJSON[any, any](ctx, client, http.MethodGet, server.URL+"/users", nil)
+	require.NoError(t, err)
+	assert.Len(t, val, 2)
+
+	// The request isn't sent if the response can't be decoded.
+	_, err = DoJSON[any, chan int](ctx, client, http.MethodPost, server.URL+"/users", user{Name: "mike"})
+	assert.ErrorIs(t, err, ErrUnsupportedType)
+	assert.Equal(t, int32(1), posts.Load())
+
+	_, err = DoJSON[map[string]any, user](ctx, client, http.MethodPost, server.URL+"/users", map[string]any{"email": "m"})
+	assert.ErrorIs(t, err, ErrUnexpectedStatus)
+	assert.EqualError(t, err, "unexpected status 400 Bad Request")
+
+	var respErr *ResponseError
+	require.ErrorAs(t, err, &respErr)
+	assert.Equal(t, http.StatusBadRequest, respErr.StatusCode)
+
+	payload, ok := ErrorPayload[apiError](err)
+	assert.True(t, ok)
+	assert.Equal(t, "invalid", payload.Code)
+	assert.Contains(t, payload.Message, `unknown field "email"`)
+
+	_, err = DoJSON[any, user](ctx, client, http.MethodGet, server.URL+"/missing", nil)
+	assert.ErrorIs(t, err, ErrUnexpectedStatus)
+
+	_, ok = ErrorPayload[apiError](err)
+	assert.False(t, ok)
+
+	_, ok = ErrorPayload[apiError](errors.New("other"))
+	assert.False(t, ok)
+}
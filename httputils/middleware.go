@@ -0,0 +1,281 @@
+package httputils
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+// Longest request id accepted from a client.
+const maxRequestIDLen = 128
+
+// Middleware wraps a handler with the handling common to the requests of a service.
+type Middleware func(next http.Handler) http.Handler
+
+// Logger is the logger of the middlewares. *log.Logger implements it.
+type Logger interface {
+	Printf(format string, args ...any)
+}
+
+type requestIDKey struct{}
+
+// Chain returns a middleware applying middlewares in order, the first being the outermost.
+//
+//	handler := httputils.Chain(
+//		httputils.RequestID(),
+//		httputils.AccessLog(logger),
+//		httputils.Recover(logger),
+//		httputils.MaxBodySize(1<<20),
+//	)(mux)
+func Chain(middlewares ...Middleware) Middleware {
+	return func(next http.Handler) http.Handler {
+		for i := len(middlewares) - 1; i >= 0; i-- {
+			next = middlewares[i](next)
+		}
+
+		return next
+	}
+}
+
+// ResponseWriter wraps an http.ResponseWriter to capture the status and the size of a response.
+type ResponseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+// NewResponseWriter wraps w, unless it's already a *ResponseWriter.
+func NewResponseWriter(w http.ResponseWriter) *ResponseWriter {
+	if rw, ok := w.(*ResponseWriter); ok {
+		return rw
+	}
+
+	return &ResponseWriter{ResponseWriter: w}
+}
+
+func (rw *ResponseWriter) WriteHeader(status int) {
+	if rw.status == 0 {
+		rw.status = status
+	}
+
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+func (rw *ResponseWriter) Write(buf []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+
+	n, err := rw.ResponseWriter.Write(buf)
+	rw.bytes += int64(n)
+
+	return n, err
+}
+
+// Flush flushes the response if the wrapped writer is an http.Flusher.
+func (rw *ResponseWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		if rw.status == 0 {
+			rw.status = http.StatusOK
+		}
+
+		flusher.Flush()
+	}
+}
+
+// Hijack lets the caller take over the connection, eg. to upgrade it to a WebSocket, if the wrapped
+// writer supports it.
+func (rw *ResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(rw.ResponseWriter).Hijack()
+}
+
+// ReadFrom copies the body of a response from src, with the io.ReaderFrom of the wrapped writer if
+// it's one eg. to send a file with sendfile.
+func (rw *ResponseWriter) ReadFrom(src io.Reader) (int64, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+
+	var (
+		n   int64
+		err error
+	)
+
+	if rf, ok := rw.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(src)
+	} else {
+		n, err = io.Copy(writerOnly{rw.ResponseWriter}, src)
+	}
+
+	rw.bytes += n
+
+	return n, err
+}
+
+// writerOnly hides the io.ReaderFrom of a writer from io.Copy.
+type writerOnly struct {
+	io.Writer
+}
+
+// Unwrap returns the wrapped writer, for http.ResponseController.
+func (rw *ResponseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// Status returns the status of the response, or 0 if nothing is written yet.
+func (rw *ResponseWriter) Status() int {
+	return rw.status
+}
+
+// BytesWritten returns the size of the body written so far.
+func (rw *ResponseWriter) BytesWritten() int64 {
+	return rw.bytes
+}
+
+// RequestID sets the X-Request-ID header of the request and the response to the id sent by the client,
+// or to a new random id if the client sent none or an invalid one. Read the id with RequestIDFrom.
+func RequestID() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(HeaderXRequestID)
+			if !isValidRequestID(id) {
+				id = newRequestID()
+				r.Header.Set(HeaderXRequestID, id)
+			}
+
+			w.Header().Set(HeaderXRequestID, id)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+		})
+	}
+}
+
+// RequestIDFrom returns the id set by RequestID in the context of a request, or an empty string.
+func RequestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func newRequestID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+
+	return hex.EncodeToString(buf)
+}
+
+// isValidRequestID returns true if an id is printable ascii of a reasonable length, so it can be logged.
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Recover recovers from a panic in a handler, logs it with the stack trace and responds with 500
+// Internal Server Error if the response isn't written yet. http.ErrAbortHandler is panicked again to
+// abort the response, as expected by http.Server.
+func Recover(logger Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := NewResponseWriter(w)
+
+			defer func() {
+				val := recover()
+				if val == nil {
+					return
+				}
+
+				if val == http.ErrAbortHandler {
+					panic(val)
+				}
+
+				logger.Printf("panic serving %s %s: %v\n%s", r.Method, r.URL.RequestURI(), val, debug.Stack())
+
+				if rw.Status() == 0 {
+					http.Error(rw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				}
+			}()
+
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// AccessLog logs every request with the status and the size of the response, the latency and the
+// request id set by RequestID, if any.
+func AccessLog(logger Logger) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rw := NewResponseWriter(w)
+
+			defer func() {
+				status := rw.Status()
+				if status == 0 {
+					// Nothing written, which net/http answers with 200.
+					status = http.StatusOK
+				}
+
+				msg := fmt.Sprintf("%s %s %s %d %dB %s", r.RemoteAddr, r.Method, r.URL.RequestURI(), status,
+					rw.BytesWritten(), time.Since(start))
+				if id := RequestIDFrom(r.Context()); id != "" {
+					msg += " request_id=" + id
+				}
+
+				logger.Printf("%s", msg)
+			}()
+
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
+
+// NoCache sets the headers of WriteNoCacheHeaders on every response.
+func NoCache() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			WriteNoCacheHeaders(w)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Timeout cancels the context of a request after timeout and responds with 503 Service Unavailable if
+// the handler hasn't written the response by then, see http.TimeoutHandler.
+func Timeout(timeout time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.TimeoutHandler(next, timeout, http.StatusText(http.StatusServiceUnavailable))
+	}
+}
+
+// MaxBodySize limits the size of the body of a request to limit bytes. A request declaring a larger
+// Content-Length is answered with 413 Request Entity Too Large, and reading a larger body fails with
+// *http.MaxBytesError.
+func MaxBodySize(limit int64) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > limit {
+				http.Error(w, http.StatusText(http.StatusRequestEntityTooLarge), http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,311 @@
+package httputils_test
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/cybersamx/golib/httputils"
+)
+
+func TestChain(t *testing.T) {
+	t.Parallel()
+
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name)
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	handler := Chain(trace("a"), trace("b"), trace("c"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, []string{"a", "b", "c", "handler"}, calls)
+
+	// An empty chain is the handler itself.
+	calls = nil
+	Chain()(handler).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, []string{"a", "b", "c", "handler"}, calls)
+}
+
+func TestRequestID(t *testing.T) {
+	t.Parallel()
+
+	var gotID string
+	handler := RequestID()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotID = RequestIDFrom(r.Context())
+		assert.Equal(t, gotID, r.Header.Get(HeaderXRequestID))
+	}))
+
+	tests := []struct {
+		name   string
+		id     string
+		wantID string
+	}{
+		{name: "Propagated", id: "abc-123", wantID: "abc-123"},
+		{name: "Generated", id: ""},
+		{name: "Invalid", id: "abc 123"},
+		{name: "Too long", id: strings.Repeat("a", 129)},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if tt.id != "" {
+			r.Header.Set(HeaderXRequestID, tt.id)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if tt.wantID != "" {
+			assert.Equal(t, tt.wantID, gotID, tt.name)
+		} else {
+			assert.Len(t, gotID, 32, tt.name)
+		}
+
+		assert.Equal(t, gotID, w.Header().Get(HeaderXRequestID), tt.name)
+	}
+
+	assert.Empty(t, RequestIDFrom(httptest.NewRequest(http.MethodGet, "/", nil).Context()))
+}
+
+func TestRecover(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	logger := log.New(&buf, "", 0)
+
+	t.Run("Before write", func(t *testing.T) {
+		handler := Recover(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		}))
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/items?id=1", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Contains(t, buf.String(), "panic serving GET /items?id=1: boom\n")
+		assert.Contains(t, buf.String(), "goroutine")
+	})
+
+	t.Run("After write", func(t *testing.T) {
+		handler := Recover(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+			panic(errors.New("boom"))
+		}))
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, http.StatusAccepted, w.Code)
+	})
+
+	t.Run("Abort", func(t *testing.T) {
+		handler := Recover(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic(http.ErrAbortHandler)
+		}))
+
+		assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+		})
+	})
+}
+
+func TestAccessLog(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	logger := log.New(&buf, "", 0)
+
+	handler := Chain(RequestID(), AccessLog(logger), Recover(logger))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/panic" {
+				panic("boom")
+			}
+
+			if r.URL.Path == "/empty" {
+				return
+			}
+
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, "hello")
+			w.(http.Flusher).Flush()
+		}),
+	)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/items", want: "192.0.2.1:1234 POST /items 201 5B "},
+		{path: "/empty", want: "192.0.2.1:1234 POST /empty 200 0B "},
+		{path: "/panic", want: "192.0.2.1:1234 POST /panic 500 22B "},
+	}
+
+	for _, tt := range tests {
+		buf.Reset()
+
+		r := httptest.NewRequest(http.MethodPost, tt.path, nil)
+		r.Header.Set(HeaderXRequestID, "id-1")
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		line := lines[len(lines)-1]
+		assert.True(t, strings.HasPrefix(line, tt.want), line)
+		assert.True(t, strings.HasSuffix(line, " request_id=id-1"), line)
+		assert.True(t, w.Flushed || tt.path != "/items")
+	}
+}
+
+func TestResponseWriter(t *testing.T) {
+	t.Parallel()
+
+	w := httptest.NewRecorder()
+	rw := NewResponseWriter(w)
+	assert.Same(t, rw, NewResponseWriter(rw))
+	assert.Equal(t, 0, rw.Status())
+
+	rw.WriteHeader(http.StatusNotFound)
+	n, err := rw.Write([]byte("not found"))
+	require.NoError(t, err)
+	assert.Equal(t, 9, n)
+
+	assert.Equal(t, http.StatusNotFound, rw.Status())
+	assert.Equal(t, int64(9), rw.BytesWritten())
+	assert.Same(t, w, rw.Unwrap())
+}
+
+func TestResponseWriter_ReadFrom(t *testing.T) {
+	t.Parallel()
+
+	w := httptest.NewRecorder()
+	rw := NewResponseWriter(w)
+
+	n, err := io.Copy(rw, strings.NewReader("hello"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), n)
+	assert.Equal(t, "hello", w.Body.String())
+	assert.Equal(t, http.StatusOK, rw.Status())
+	assert.Equal(t, int64(5), rw.BytesWritten())
+
+	// httptest.ResponseRecorder can't be hijacked.
+	_, _, err = rw.Hijack()
+	assert.ErrorIs(t, err, http.ErrNotSupported)
+}
+
+func TestResponseWriter_Hijack(t *testing.T) {
+	t.Parallel()
+
+	var logs bytes.Buffer
+	logger := log.New(&logs, "", 0)
+
+	handler := Chain(Recover(logger), AccessLog(logger))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hijacker, ok := w.(http.Hijacker)
+		if !assert.True(t, ok) {
+			return
+		}
+
+		conn, buf, err := hijacker.Hijack()
+		if !assert.NoError(t, err) {
+			return
+		}
+		defer conn.Close()
+
+		buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+		buf.Flush()
+	}))
+
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = io.WriteString(conn, "GET / HTTP/1.1\r\nHost: example.com\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+	require.NoError(t, err)
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	assert.Equal(t, "websocket", resp.Header.Get("Upgrade"))
+}
+
+func TestNoCache(t *testing.T) {
+	t.Parallel()
+
+	handler := NoCache()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.Equal(t, "no-cache, private, max-age=0", w.Header().Get(HeaderCacheControl))
+	assert.Equal(t, "no-cache", w.Header().Get(HeaderPragma))
+}
+
+func TestTimeout(t *testing.T) {
+	t.Parallel()
+
+	handler := Timeout(10 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestMaxBodySize(t *testing.T) {
+	t.Parallel()
+
+	handler := MaxBodySize(4)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		w.Write(body)
+	}))
+
+	tests := []struct {
+		name       string
+		body       io.Reader
+		wantStatus int
+	}{
+		{name: "Small", body: strings.NewReader("abcd"), wantStatus: http.StatusOK},
+		{name: "Content-Length", body: strings.NewReader("abcde"), wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "Chunked", body: io.MultiReader(strings.NewReader("abcde")), wantStatus: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", tt.body))
+		assert.Equal(t, tt.wantStatus, w.Code, tt.name)
+	}
+}
@@ -0,0 +1,292 @@
+package httputils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+var ErrInvalidProxy = errors.New("invalid trusted proxy")
+
+// TrustedProxies is the list of the proxies trusted to set the forwarding headers of a request.
+type TrustedProxies struct {
+	prefixes []netip.Prefix
+}
+
+// Forwarded is the origin of a request resolved from the forwarding headers set by trusted proxies.
+type Forwarded struct {
+	ClientIP string // IP of the client, or its obfuscated identifier eg. _hidden or unknown in Forwarded.
+	Scheme   string // http or https.
+	Host     string // Host requested by the client, with the port if any.
+}
+
+type forwardedKey struct{}
+
+// forwardedHop is the request received by a proxy, or by the server, from a client.
+type forwardedHop struct {
+	client string
+	proto  string
+	host   string
+}
+
+// NewTrustedProxies returns the proxies of a list of CIDRs eg. 10.0.0.0/8, or IPs eg. 127.0.0.1. It
+// returns ErrInvalidProxy if a CIDR is invalid.
+func NewTrustedProxies(cidrs ...string) (*TrustedProxies, error) {
+	proxies := TrustedProxies{prefixes: make([]netip.Prefix, 0, len(cidrs))}
+
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(cidr)
+			if addrErr != nil {
+				return nil, fmt.Errorf("%w %q: %w", ErrInvalidProxy, cidr, err)
+			}
+
+			prefix = netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen())
+		}
+
+		proxies.prefixes = append(proxies.prefixes, prefix.Masked())
+	}
+
+	return &proxies, nil
+}
+
+// IsTrusted returns true if an IP, with an optional port, is a trusted proxy.
+func (p *TrustedProxies) IsTrusted(ip string) bool {
+	if p == nil {
+		return false
+	}
+
+	addr, ok := parseIP(ip)
+	if !ok {
+		return false
+	}
+
+	for _, prefix := range p.prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Resolve returns the client IP, the scheme and the host of a request, from the headers Forwarded of
+// RFC 7239, or X-Forwarded-For, X-Forwarded-Proto and X-Forwarded-Host if Forwarded isn't set. The
+// headers are read from the nearest proxy back to the client and only while the proxies are trusted,
+// so a client can't spoof them. A value not forwarded is the value of the request received by the
+// first trusted proxy, or by the server.
+func (p *TrustedProxies) Resolve(r *http.Request) Forwarded {
+	peer := forwardedHop{client: r.RemoteAddr, proto: "http", host: r.Host}
+	if r.TLS != nil {
+		peer.proto = "https"
+	}
+
+	if addr, err := netip.ParseAddrPort(r.RemoteAddr); err == nil {
+		peer.client = addr.Addr().Unmap().String()
+	}
+
+	hop := peer
+	if p.IsTrusted(peer.client) {
+		hops := forwardedHops(r.Header)
+
+		// Walk back from the hop of the nearest proxy, up to the first client that isn't trusted.
+		for i := len(hops) - 1; i >= 0; i-- {
+			if hops[i].proto == "" {
+				hops[i].proto = hop.proto
+			}
+
+			if hops[i].host == "" {
+				hops[i].host = hop.host
+			}
+
+			hop = hops[i]
+			if !p.IsTrusted(hop.client) {
+				break
+			}
+		}
+	}
+
+	return Forwarded{ClientIP: hop.client, Scheme: strings.ToLower(hop.proto), Host: hop.host}
+}
+
+// forwardedHops returns the hops of the forwarding headers, from the client to the nearest proxy.
+func forwardedHops(header http.Header) []forwardedHop {
+	if values := header.Values(HeaderForwarded); len(values) > 0 {
+		return parseForwarded(values)
+	}
+
+	var hops []forwardedHop
+	for _, client := range splitList(header.Values(HeaderXForwardedFor)) {
+		hops = append(hops, forwardedHop{client: client})
+	}
+
+	if len(hops) == 0 {
+		return nil
+	}
+
+	// A proxy either appends to X-Forwarded-Proto and X-Forwarded-Host like X-Forwarded-For, or overwrites
+	// them, in which case they are the values of the request received by the nearest proxy setting them.
+	for _, name := range []string{HeaderXForwardedProto, HeaderXForwardedHost} {
+		values := splitList(header.Values(name))
+		if len(values) == 0 {
+			continue
+		}
+
+		for i := range hops {
+			val := values[len(values)-1]
+			if len(values) == len(hops) {
+				val = values[i]
+			}
+
+			if name == HeaderXForwardedProto {
+				hops[i].proto = val
+			} else {
+				hops[i].host = val
+			}
+		}
+	}
+
+	return hops
+}
+
+// parseForwarded returns the hops of the elements of Forwarded headers eg.
+// for=192.0.2.60;proto=https;host=example.com, for="[2001:db8::1]:4711".
+func parseForwarded(values []string) []forwardedHop {
+	var hops []forwardedHop
+
+	for _, value := range values {
+		for _, element := range splitQuoted(value, ',') {
+			var hop forwardedHop
+
+			for _, pair := range splitQuoted(element, ';') {
+				name, val, _ := strings.Cut(strings.TrimSpace(pair), "=")
+				val = strings.Trim(strings.TrimSpace(val), `"`)
+
+				switch strings.ToLower(strings.TrimSpace(name)) {
+				case "for":
+					hop.client = forwardedNode(val)
+				case "proto":
+					hop.proto = val
+				case "host":
+					hop.host = val
+				}
+			}
+
+			if hop.client == "" {
+				hop.client = "unknown"
+			}
+
+			hops = append(hops, hop)
+		}
+	}
+
+	return hops
+}
+
+// forwardedNode returns the IP of a node of Forwarded without the port eg. 2001:db8::1 for
+// [2001:db8::1]:4711, or the node as is if it's obfuscated eg. _hidden.
+func forwardedNode(node string) string {
+	if addr, err := netip.ParseAddrPort(node); err == nil {
+		return addr.Addr().Unmap().String()
+	}
+
+	if addr, ok := parseIP(strings.Trim(node, "[]")); ok {
+		return addr.String()
+	}
+
+	return node
+}
+
+// parseIP parses an IP with an optional port.
+func parseIP(ip string) (netip.Addr, bool) {
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return addr.Unmap(), true
+}
+
+// splitList returns the items of comma separated header values.
+func splitList(values []string) []string {
+	var items []string
+
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+
+	return items
+}
+
+// ProxyHeaders resolves the origin of every request with the trusted proxies, see Resolve, and sets
+// the RemoteAddr of the request to the client IP with port 0 eg. 198.51.100.1:0, and its Host to the
+// forwarded host. The RemoteAddr is left as is if the client isn't forwarded or is obfuscated eg.
+// for=_hidden. Read the origin with ForwardedFrom. A nil proxies trusts no proxy.
+func ProxyHeaders(proxies *TrustedProxies) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fwd := proxies.Resolve(r)
+
+			r = r.WithContext(context.WithValue(r.Context(), forwardedKey{}, fwd))
+			r.RemoteAddr = remoteAddr(r.RemoteAddr, fwd.ClientIP)
+			r.Host = fwd.Host
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// remoteAddr returns the host:port of the client IP to set as the RemoteAddr of a request, keeping
+// the form expected by net.SplitHostPort.
+func remoteAddr(addr, clientIP string) string {
+	ip, ok := parseIP(clientIP)
+	if !ok {
+		return addr
+	}
+
+	if remote, ok := parseIP(addr); ok && remote == ip {
+		return addr
+	}
+
+	return net.JoinHostPort(ip.String(), "0")
+}
+
+// ForwardedFrom returns the origin of a request set by ProxyHeaders in its context.
+func ForwardedFrom(ctx context.Context) (Forwarded, bool) {
+	fwd, ok := ctx.Value(forwardedKey{}).(Forwarded)
+	return fwd, ok
+}
+
+// RedirectHTTPS redirects a request received over http, as resolved with the trusted proxies or by
+// ProxyHeaders, to the same url over https with 308 Permanent Redirect, which keeps the method and body.
+func RedirectHTTPS(proxies *TrustedProxies) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fwd, ok := ForwardedFrom(r.Context())
+			if !ok {
+				fwd = proxies.Resolve(r)
+			}
+
+			if fwd.Scheme == "https" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			host := strings.TrimSuffix(fwd.Host, ":80")
+			http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package httputils_test
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	. "github.com/cybersamx/golib/httputils"
+)
+
+func TestNewTrustedProxies(t *testing.T) {
+	t.Parallel()
+
+	proxies, err := NewTrustedProxies("10.0.0.0/8", "192.168.1.1", "2001:db8::/32")
+	require.NoError(t, err)
+
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "10.1.2.3", want: true},
+		{ip: "10.1.2.3:8080", want: true},
+		{ip: "::ffff:10.1.2.3", want: true},
+		{ip: "192.168.1.1", want: true},
+		{ip: "192.168.1.2", want: false},
+		{ip: "[2001:db8::1]:443", want: true},
+		{ip: "2001:db9::1", want: false},
+		{ip: "unknown", want: false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, proxies.IsTrusted(tt.ip), tt.ip)
+	}
+
+	var none *TrustedProxies
+	assert.False(t, none.IsTrusted("10.1.2.3"))
+
+	_, err = NewTrustedProxies("10.0.0.0/33")
+	assert.ErrorIs(t, err, ErrInvalidProxy)
+}
+
+func TestTrustedProxies_Resolve(t *testing.T) {
+	t.Parallel()
+
+	proxies, err := NewTrustedProxies("10.0.0.0/8")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		tls        bool
+		header     http.Header
+		want       Forwarded
+	}{
+		{
+			name:       "Direct",
+			remoteAddr: "203.0.113.1:5000",
+			header:     http.Header{HeaderXForwardedFor: {"198.51.100.1"}, HeaderXForwardedProto: {"https"}},
+			want:       Forwarded{ClientIP: "203.0.113.1", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:       "Direct TLS",
+			remoteAddr: "203.0.113.1:5000",
+			tls:        true,
+			want:       Forwarded{ClientIP: "203.0.113.1", Scheme: "https", Host: "example.com"},
+		},
+		{
+			name:       "Trusted proxy without headers",
+			remoteAddr: "10.0.0.1:5000",
+			want:       Forwarded{ClientIP: "10.0.0.1", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:       "X-Forwarded",
+			remoteAddr: "10.0.0.1:5000",
+			header: http.Header{
+				HeaderXForwardedFor:   {"198.51.100.1, 10.0.0.2"},
+				HeaderXForwardedProto: {"HTTPS"},
+				HeaderXForwardedHost:  {"api.example.com"},
+			},
+			want: Forwarded{ClientIP: "198.51.100.1", Scheme: "https", Host: "api.example.com"},
+		},
+		{
+			name:       "Spoofed X-Forwarded-For",
+			remoteAddr: "10.0.0.1:5000",
+			header: http.Header{
+				HeaderXForwardedFor:   {"1.1.1.1, 198.51.100.1", "10.0.0.2"},
+				HeaderXForwardedProto: {"http, https, https"},
+			},
+			want: Forwarded{ClientIP: "198.51.100.1", Scheme: "https", Host: "example.com"},
+		},
+		{
+			name:       "All trusted",
+			remoteAddr: "10.0.0.1:5000",
+			header:     http.Header{HeaderXForwardedFor: {"10.0.0.3, 10.0.0.2"}},
+			want:       Forwarded{ClientIP: "10.0.0.3", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:       "Forwarded",
+			remoteAddr: "10.0.0.1:5000",
+			header: http.Header{
+				HeaderForwarded: {
+					`for=192.0.2.60;proto=https;host="shop.example.com", for="[2001:db8::1]:4711"`,
+					"for=10.0.0.2;proto=http",
+				},
+				HeaderXForwardedFor: {"198.51.100.1"},
+			},
+			want: Forwarded{ClientIP: "2001:db8::1", Scheme: "http", Host: "example.com"},
+		},
+		{
+			name:       "Forwarded trusted chain",
+			remoteAddr: "10.0.0.1:5000",
+			header: http.Header{
+				HeaderForwarded: {`For=192.0.2.60;Proto=https;Host=shop.example.com, for=10.0.0.2`},
+			},
+			want: Forwarded{ClientIP: "192.0.2.60", Scheme: "https", Host: "shop.example.com"},
+		},
+		{
+			name:       "Forwarded obfuscated",
+			remoteAddr: "10.0.0.1:5000",
+			header:     http.Header{HeaderForwarded: {"for=_hidden;proto=https"}},
+			want:       Forwarded{ClientIP: "_hidden", Scheme: "https", Host: "example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "http://example.com/", nil)
+		r.RemoteAddr = tt.remoteAddr
+		if tt.tls {
+			r.TLS = &tls.ConnectionState{}
+		}
+
+		for name, values := range tt.header {
+			r.Header[name] = values
+		}
+
+		assert.Equal(t, tt.want, proxies.Resolve(r), tt.name)
+	}
+}
+
+func TestProxyHeaders(t *testing.T) {
+	t.Parallel()
+
+	proxies, err := NewTrustedProxies("10.0.0.0/8")
+	require.NoError(t, err)
+
+	var (
+		got       Forwarded
+		gotRemote string
+		gotHost   string
+	)
+
+	handler := Chain(ProxyHeaders(proxies), RedirectHTTPS(nil))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = ForwardedFrom(r.Context())
+		gotRemote, gotHost = r.RemoteAddr, r.Host
+	}))
+
+	r := httptest.NewRequest(http.MethodPost, "http://example.com/items?id=1", nil)
+	r.RemoteAddr = "10.0.0.1:5000"
+	r.Header.Set(HeaderXForwardedFor, "198.51.100.1")
+	r.Header.Set(HeaderXForwardedProto, "https")
+	r.Header.Set(HeaderXForwardedHost, "api.example.com")
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, Forwarded{ClientIP: "198.51.100.1", Scheme: "https", Host: "api.example.com"}, got)
+	assert.Equal(t, "198.51.100.1:0", gotRemote)
+	assert.Equal(t, "api.example.com", gotHost)
+
+	host, _, err := net.SplitHostPort(gotRemote)
+	require.NoError(t, err)
+	assert.Equal(t, "198.51.100.1", host)
+
+	// The RemoteAddr of a client that isn't forwarded or is obfuscated is kept.
+	r = httptest.NewRequest(http.MethodGet, "https://example.com/", nil)
+	r.RemoteAddr = "203.0.113.1:5000"
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, "203.0.113.1:5000", gotRemote)
+
+	r = httptest.NewRequest(http.MethodGet, "https://example.com/", nil)
+	r.RemoteAddr = "10.0.0.1:5000"
+	r.Header.Set(HeaderForwarded, "for=_hidden;proto=https")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+	assert.Equal(t, "_hidden", got.ClientIP)
+	assert.Equal(t, "10.0.0.1:5000", gotRemote)
+
+	_, ok := ForwardedFrom(httptest.NewRequest(http.MethodGet, "/", nil).Context())
+	assert.False(t, ok)
+}
+
+func TestRedirectHTTPS(t *testing.T) {
+	t.Parallel()
+
+	proxies, err := NewTrustedProxies("10.0.0.1")
+	require.NoError(t, err)
+
+	handler := RedirectHTTPS(proxies)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name         string
+		url          string
+		remoteAddr   string
+		proto        string
+		wantStatus   int
+		wantLocation string
+	}{
+		{
+			name: "Plain http", url: "http://example.com:80/a?b=c", remoteAddr: "203.0.113.1:5000",
+			wantStatus: http.StatusPermanentRedirect, wantLocation: "https://example.com/a?b=c",
+		},
+		{
+			name: "Untrusted proto", url: "http://example.com/", remoteAddr: "203.0.113.1:5000", proto: "https",
+			wantStatus: http.StatusPermanentRedirect, wantLocation: "https://example.com/",
+		},
+		{
+			name: "Trusted proto", url: "http://example.com/", remoteAddr: "10.0.0.1:5000", proto: "https",
+			wantStatus: http.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, tt.url, nil)
+		r.RemoteAddr = tt.remoteAddr
+		if tt.proto != "" {
+			r.Header.Set(HeaderXForwardedFor, "198.51.100.1")
+			r.Header.Set(HeaderXForwardedProto, tt.proto)
+		}
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		assert.Equal(t, tt.wantStatus, w.Code, tt.name)
+		assert.Equal(t, tt.wantLocation, w.Header().Get("Location"), tt.name)
+	}
+}
@@ -10,21 +10,75 @@ import (
 
 var (
 	ErrIncorrectType = errors.New("incorrect type parameter")
+	ErrTrailingData  = errors.New("unexpected data after the json value")
 )
 
+// ParseOption configures how ParseJSON parses a json value.
+type ParseOption func(opts *parseOptions)
+
+type parseOptions struct {
+	disallowUnknownFields bool
+	disallowTrailingData  bool
+}
+
+// WithDisallowUnknownFields makes ParseJSON return an error if the json object has a field that isn't
+// in the struct of type T.
+func WithDisallowUnknownFields() ParseOption {
+	return func(opts *parseOptions) {
+		opts.disallowUnknownFields = true
+	}
+}
+
+// WithDisallowTrailingData makes ParseJSON return ErrTrailingData if the json value is followed by
+// anything but whitespace eg. {"a":1} garbage.
+func WithDisallowTrailingData() ParseOption {
+	return func(opts *parseOptions) {
+		opts.disallowTrailingData = true
+	}
+}
+
+// CheckJSONType returns ErrIncorrectType if ParseJSON doesn't parse json into a value of type T.
+func CheckJSONType[T any]() error {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+
+	switch typ.Kind() {
+	case reflect.Struct, reflect.Pointer, reflect.Map, reflect.Slice, reflect.Array, reflect.Interface:
+		return nil
+	}
+
+	return fmt.Errorf("the type parameter T %s must be of struct, pointer, map, slice or interface type: %w",
+		typ, ErrIncorrectType)
+}
+
 // ParseJSON parses a json string and construct it into a Go object of type defined by (generic) type parameter T.
-// T must be a pointer type, otherwise the function returns an error ErrIncorrectType.
-func ParseJSON[T any](reader io.Reader) (T, error) {
+// T must be a struct, pointer, map, slice, array or interface type eg. any, otherwise the function returns an
+// error ErrIncorrectType. An empty json string is parsed into the zero value of T.
+func ParseJSON[T any](reader io.Reader, opts ...ParseOption) (T, error) {
 	var target T
-	kind := reflect.ValueOf(target).Kind()
-	if kind != reflect.Struct && kind != reflect.Pointer && kind != reflect.Map {
-		return *new(T), fmt.Errorf("the type parameter T %T must be of struct type: %w", target, ErrIncorrectType)
+	if err := CheckJSONType[T](); err != nil {
+		return target, err
+	}
+
+	var options parseOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	decoder := json.NewDecoder(reader)
+	if options.disallowUnknownFields {
+		decoder.DisallowUnknownFields()
 	}
 
-	err := json.NewDecoder(reader).Decode(&target)
+	err := decoder.Decode(&target)
 	if err != nil && !errors.Is(err, io.EOF) {
 		return *new(T), fmt.Errorf("failed to unmarhsal json to %T when parsing a json object: %w", target, err)
 	}
 
+	if err == nil && options.disallowTrailingData {
+		if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+			return *new(T), ErrTrailingData
+		}
+	}
+
 	return target, nil
 }
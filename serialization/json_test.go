@@ -63,4 +63,48 @@ func TestParseJSON(t *testing.T) {
 		diff := pretty.Compare(wantPersonMap, objMap)
 		assert.Empty(t, diff)
 	})
+
+	t.Run("With slice and any types", func(t *testing.T) {
+		json := `[{"name": "mike", "age": 25}, {"name": "jane", "age": 30}]`
+
+		objs, err := ParseJSON[[]person](strings.NewReader(json))
+		assert.NoError(t, err)
+		assert.Equal(t, []person{{Name: "mike", Age: 25}, {Name: "jane", Age: 30}}, objs)
+
+		obj, err := ParseJSON[any](strings.NewReader(`[1, "a"]`))
+		assert.NoError(t, err)
+		assert.Equal(t, []any{float64(1), "a"}, obj)
+
+		assert.NoError(t, CheckJSONType[[2]int]())
+		assert.ErrorIs(t, CheckJSONType[int](), ErrIncorrectType)
+	})
+
+	t.Run("With trailing data disallowed", func(t *testing.T) {
+		json := `{"name": "mike"} garbage`
+
+		_, err := ParseJSON[person](strings.NewReader(json), WithDisallowTrailingData())
+		assert.ErrorIs(t, err, ErrTrailingData)
+
+		_, err = ParseJSON[person](strings.NewReader(`{"name": "mike"} {}`), WithDisallowTrailingData())
+		assert.ErrorIs(t, err, ErrTrailingData)
+
+		obj, err := ParseJSON[person](strings.NewReader("{\"name\": \"mike\"}\n"), WithDisallowTrailingData())
+		assert.NoError(t, err)
+		assert.Equal(t, person{Name: "mike"}, obj)
+
+		obj, err = ParseJSON[person](strings.NewReader(json))
+		assert.NoError(t, err)
+		assert.Equal(t, person{Name: "mike"}, obj)
+	})
+
+	t.Run("With unknown fields disallowed", func(t *testing.T) {
+		json := `{"name": "mike", "age": 25, "email": "mike@example.com"}`
+
+		_, err := ParseJSON[person](strings.NewReader(json), WithDisallowUnknownFields())
+		assert.ErrorContains(t, err, `unknown field "email"`)
+
+		obj, err := ParseJSON[person](strings.NewReader(json))
+		assert.NoError(t, err)
+		assert.Equal(t, person{Name: "mike", Age: 25}, obj)
+	})
 }